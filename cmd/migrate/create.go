@@ -0,0 +1,137 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	dbConn "go-rest-template/internal/db/conn"
+)
+
+// flags of the create command
+type createOptions struct {
+	sequential bool
+	template   string
+	table      string
+	column     string
+	columnType string
+}
+
+func (o *createOptions) register(fs *flag.FlagSet) {
+	fs.BoolVar(&o.sequential, "seq", false, "use the next sequential 6-digit version instead of a timestamp")
+	fs.StringVar(&o.template, "template", "create_table", "template: create_table, add_column, add_index or empty")
+	fs.StringVar(&o.table, "table", "your_table", "table name used by the template")
+	fs.StringVar(&o.column, "column", "your_column", "column name used by the add_column and add_index templates")
+	fs.StringVar(&o.columnType, "type", "TEXT", "column type used by the add_column template")
+}
+
+var nameSanitizer = regexp.MustCompile(`[^a-z0-9_]+`)
+
+// writes a new pair of migration files and returns their paths
+func createMigration(dir string, name string, opts createOptions) ([]string, error) {
+	name = strings.Trim(nameSanitizer.ReplaceAllString(strings.ToLower(name), "_"), "_")
+	if name == "" {
+		return nil, fmt.Errorf("invalid migration name")
+	}
+
+	upSQL, downSQL, err := generateTemplates(opts)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := os.MkdirAll(dir, os.ModePerm); err != nil {
+		return nil, fmt.Errorf("failed to create migrations dir: %w", err)
+	}
+
+	// refuse to add to a directory that already has conflicts
+	if err := dbConn.ValidateMigrations(dir); err != nil {
+		return nil, fmt.Errorf("existing migrations are invalid: %w", err)
+	}
+
+	var version string
+	if opts.sequential {
+		index, err := getNextMigrationIndex(dir)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get next migration index: %w", err)
+		}
+		version = fmt.Sprintf("%06d", index)
+	} else {
+		version = time.Now().UTC().Format("20060102150405")
+	}
+
+	if existing, _ := filepath.Glob(filepath.Join(dir, version+"_*.sql")); len(existing) > 0 {
+		return nil, fmt.Errorf("migration version %s already exists: %s", version, existing[0])
+	}
+
+	base := fmt.Sprintf("%s_%s", version, name)
+	upPath := filepath.Join(dir, base+".up.sql")
+	downPath := filepath.Join(dir, base+".down.sql")
+
+	if err := os.WriteFile(upPath, []byte(upSQL), 0644); err != nil {
+		return nil, fmt.Errorf("failed to write %s: %w", upPath, err)
+	}
+	if err := os.WriteFile(downPath, []byte(downSQL), 0644); err != nil {
+		return nil, fmt.Errorf("failed to write %s: %w", downPath, err)
+	}
+
+	return []string{upPath, downPath}, nil
+}
+
+func getNextMigrationIndex(dir string) (int, error) {
+	files, err := os.ReadDir(dir)
+	if err != nil {
+		return 1, nil // first migration
+	}
+
+	re := regexp.MustCompile(`^(\d{6})_.*\.up\.sql$`)
+	var indices []int
+
+	for _, f := range files {
+		match := re.FindStringSubmatch(f.Name())
+		if len(match) == 2 {
+			n, err := strconv.Atoi(match[1])
+			if err == nil {
+				indices = append(indices, n)
+			}
+		}
+	}
+
+	if len(indices) == 0 {
+		return 1, nil
+	}
+
+	sort.Ints(indices)
+	return indices[len(indices)-1] + 1, nil
+}
+
+func generateTemplates(opts createOptions) (string, string, error) {
+	table, column := opts.table, opts.column
+	switch opts.template {
+	case "create_table":
+		return fmt.Sprintf(`CREATE TABLE
+    IF NOT EXISTS %s (
+        id SERIAL PRIMARY KEY,
+        created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+        updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+    );
+`, table),
+			fmt.Sprintf("DROP TABLE IF EXISTS %s;\n", table), nil
+	case "add_column":
+		return fmt.Sprintf("ALTER TABLE %s ADD COLUMN IF NOT EXISTS %s %s;\n", table, column, opts.columnType),
+			fmt.Sprintf("ALTER TABLE %s DROP COLUMN IF EXISTS %s;\n", table, column), nil
+	case "add_index":
+		index := fmt.Sprintf("idx_%s_%s", table, column)
+		return fmt.Sprintf("CREATE INDEX IF NOT EXISTS %s ON %s (%s);\n", index, table, column),
+			fmt.Sprintf("DROP INDEX IF EXISTS %s;\n", index), nil
+	case "empty":
+		return "-- write your up migration here\n", "-- write your down migration here\n", nil
+	default:
+		return "", "", fmt.Errorf("unknown template %q (create_table, add_column, add_index, empty)", opts.template)
+	}
+}
@@ -0,0 +1,346 @@
+// Command migrate manages the database schema migrations, see usage below.
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+
+	dbConn "go-rest-template/internal/db/conn"
+	_ "go-rest-template/internal/db/migrations"
+	"go-rest-template/internal/db/seeds"
+	"go-rest-template/pkg/config"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+const (
+	exitOK       = 0
+	exitFailure  = 1
+	exitUsage    = 2
+	exitProblems = 3
+)
+
+const usage = `Usage: migrate <command> [flags] [args]
+
+Commands:
+  up                apply all pending migrations
+  down [n]          roll back the last n migrations (default 1)
+  to <version>      migrate up or down to the given version ("0" rolls back everything)
+  status            list migrations and whether they are applied
+  create <name>     create a new pair of .up.sql/.down.sql files
+                    [--seq] [--template=create_table|add_column|add_index|empty]
+                    [--table=name] [--column=name] [--type=TEXT]
+  force <version>   mark migrations up to version as applied without running them
+  verify            check applied migrations against the local files
+  snapshot          replay all migrations in a scratch schema and write the catalog snapshot,
+                    checking it matches the schema sqlc reads from the .sql files
+                    [--snapshot=schema/snapshot.json] [--check]
+  diff              compare the snapshot file against the live database
+                    [--snapshot=schema/snapshot.json] [--schema=public]
+  seed              apply the seeds of an environment that were not applied yet
+                    [--env=dev|test|demo] [--seeds-dir=schema/seeds]
+                    [--force] to seed even when GO_ENV is production
+
+Flags:
+  --database-url    database url (defaults to DB_URL)
+  --dir             migrations directory (default "schema/migrations")
+  --json            print machine-readable JSON output
+
+Exit codes: 0 success, 1 failure, 2 usage error, 3 verify found problems or the schema drifted.
+`
+
+// options shared by every command
+type options struct {
+	databaseURL string
+	dir         string
+	json        bool
+	snapshot    string
+	schema      string
+	check       bool
+	env         string
+	seedsDir    string
+	force       bool
+}
+
+// result is printed as JSON when --json is set
+type result struct {
+	Command    string                  `json:"command"`
+	OK         bool                    `json:"ok"`
+	Error      string                  `json:"error,omitempty"`
+	Applied    []string                `json:"applied,omitempty"`
+	Version    string                  `json:"version,omitempty"`
+	Migrations []dbConn.MigrationState `json:"migrations,omitempty"`
+	Problems   []string                `json:"problems,omitempty"`
+	Files      []string                `json:"files,omitempty"`
+	Diffs      []dbConn.SchemaDiff     `json:"diffs,omitempty"`
+	SqlcDiffs  []dbConn.SchemaDiff     `json:"sqlc_diffs,omitempty"`
+}
+
+func main() {
+	os.Exit(run(os.Args[1:]))
+}
+
+func run(args []string) int {
+	if len(args) == 0 || args[0] == "-h" || args[0] == "--help" || args[0] == "help" {
+		fmt.Fprint(os.Stderr, usage)
+		if len(args) == 0 {
+			return exitUsage
+		}
+		return exitOK
+	}
+
+	command := args[0]
+	opts := options{}
+	fs := flag.NewFlagSet(command, flag.ContinueOnError)
+	fs.Usage = func() { fmt.Fprint(os.Stderr, usage) }
+	fs.StringVar(&opts.databaseURL, "database-url", "", "database url (defaults to DB_URL)")
+	fs.StringVar(&opts.dir, "dir", "schema/migrations", "migrations directory")
+	fs.BoolVar(&opts.json, "json", false, "print JSON output")
+	create := createOptions{}
+	switch command {
+	case "create":
+		create.register(fs)
+	case "snapshot":
+		fs.StringVar(&opts.snapshot, "snapshot", "schema/snapshot.json", "schema snapshot file")
+		fs.BoolVar(&opts.check, "check", false, "compare against the snapshot file instead of writing it")
+	case "diff":
+		fs.StringVar(&opts.snapshot, "snapshot", "schema/snapshot.json", "schema snapshot file")
+		fs.StringVar(&opts.schema, "schema", "public", "schema of the live database to compare")
+	case "seed":
+		fs.StringVar(&opts.env, "env", seeds.EnvDev, "seed environment: dev, test or demo")
+		fs.StringVar(&opts.seedsDir, "seeds-dir", "schema/seeds", "seeds directory")
+		fs.BoolVar(&opts.force, "force", false, "seed even when GO_ENV is production")
+	}
+	rest, err := parseFlags(fs, args[1:])
+	if err != nil {
+		return exitUsage
+	}
+
+	res := result{Command: command}
+	code := exitOK
+
+	switch command {
+	case "create":
+		if len(rest) != 1 {
+			return usageError("create requires a migration name")
+		}
+		res.Files, err = createMigration(opts.dir, rest[0], create)
+	case "up", "down", "to", "status", "force", "verify", "snapshot", "diff", "seed":
+		code, err = runWithDB(command, rest, opts, &res)
+	default:
+		return usageError(fmt.Sprintf("unknown command %q", command))
+	}
+
+	if err != nil {
+		res.Error = err.Error()
+		if code == exitOK {
+			code = exitFailure
+		}
+	}
+	res.OK = code == exitOK
+	printResult(opts, res)
+	return code
+}
+
+// parses the flags of a command wherever they appear, so that both
+// `migrate down -dir x 2` and `migrate down 2 -dir x` work, and returns the
+// positional arguments. Arguments after "--" are never treated as flags.
+func parseFlags(fs *flag.FlagSet, args []string) ([]string, error) {
+	var positional []string
+	for {
+		if err := fs.Parse(args); err != nil {
+			return nil, err
+		}
+		rest := fs.Args()
+		// Parse drops a "--" terminator, everything after it is positional
+		if n := len(args) - len(rest); n > 0 && args[n-1] == "--" {
+			return append(positional, rest...), nil
+		}
+		if len(rest) == 0 {
+			return positional, nil
+		}
+		positional = append(positional, rest[0])
+		args = rest[1:]
+	}
+}
+
+// runs the commands that need a database connection
+func runWithDB(command string, args []string, opts options, res *result) (int, error) {
+	// loaded after the flags so --help and usage errors never depend on the environment
+	cfg, err := config.Load(nil, nil)
+	if err != nil {
+		return exitUsage, fmt.Errorf("loading configuration: %w", err)
+	}
+	if opts.databaseURL == "" {
+		opts.databaseURL = cfg.DB_URL
+	}
+	if opts.databaseURL == "" {
+		return exitUsage, fmt.Errorf("no database url: set DB_URL or pass --database-url")
+	}
+
+	// validate arguments before connecting
+	n := 1
+	switch command {
+	case "down":
+		if len(args) > 1 {
+			return exitUsage, fmt.Errorf("down takes at most one argument")
+		}
+		if len(args) == 1 {
+			v, err := strconv.Atoi(args[0])
+			if err != nil || v < 1 {
+				return exitUsage, fmt.Errorf("invalid number of migrations %q", args[0])
+			}
+			n = v
+		}
+	case "to", "force":
+		if len(args) != 1 {
+			return exitUsage, fmt.Errorf("%s requires a version", command)
+		}
+	default:
+		if len(args) != 0 {
+			return exitUsage, fmt.Errorf("%s takes no arguments", command)
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Minute)
+	defer cancel()
+
+	pool, err := dbConn.Connect(opts.databaseURL)
+	if err != nil {
+		return exitFailure, err
+	}
+	defer pool.Close()
+
+	switch command {
+	case "up":
+		res.Applied, err = dbConn.MigrateUp(ctx, pool, opts.dir)
+	case "down":
+		res.Applied, err = dbConn.MigrateDown(ctx, pool, opts.dir, n)
+	case "to":
+		res.Applied, err = dbConn.MigrateTo(ctx, pool, opts.dir, args[0])
+	case "force":
+		err = dbConn.ForceVersion(ctx, pool, opts.dir, args[0])
+	case "status":
+		res.Migrations, err = dbConn.GetMigrationStatus(ctx, pool, opts.dir)
+	case "verify":
+		res.Problems, err = dbConn.VerifyMigrations(ctx, pool, opts.dir)
+		if err == nil && len(res.Problems) > 0 {
+			return exitProblems, fmt.Errorf("found %d problem(s)", len(res.Problems))
+		}
+	case "snapshot":
+		return runSnapshot(ctx, pool, opts, res)
+	case "diff":
+		return runDiff(ctx, pool, opts, res)
+	case "seed":
+		res.Applied, err = seeds.Run(ctx, pool, opts.seedsDir, opts.env, opts.force)
+	}
+	if err != nil {
+		return exitFailure, err
+	}
+	res.Version, _, err = dbConn.GetMigrationVersion(ctx, pool)
+	if err != nil {
+		return exitFailure, fmt.Errorf("reading current version: %w", err)
+	}
+	return exitOK, nil
+}
+
+// writes the snapshot produced by replaying all migrations, or with --check
+// compares it against the committed file. Either way the snapshot is compared
+// against the schema sqlc reads, which leaves out Go migrations.
+func runSnapshot(ctx context.Context, pool *pgxpool.Pool, opts options, res *result) (int, error) {
+	snap, err := dbConn.SnapshotFromMigrations(ctx, pool, opts.dir)
+	if err != nil {
+		return exitFailure, err
+	}
+	sqlcSnap, err := dbConn.SnapshotForSqlc(ctx, pool, opts.dir)
+	if err != nil {
+		return exitFailure, fmt.Errorf("replaying the SQL files read by sqlc: %w", err)
+	}
+	res.SqlcDiffs = dbConn.DiffSnapshots(snap, sqlcSnap)
+
+	if !opts.check {
+		if err := dbConn.WriteSnapshot(opts.snapshot, snap); err != nil {
+			return exitFailure, err
+		}
+		res.Files = []string{opts.snapshot}
+	} else {
+		committed, err := dbConn.ReadSnapshot(opts.snapshot)
+		if err != nil {
+			return exitFailure, err
+		}
+		res.Diffs = dbConn.DiffSnapshots(committed, snap)
+		if len(res.Diffs) > 0 {
+			return exitProblems, fmt.Errorf("%s is out of date with the migrations, run `migrate snapshot`", opts.snapshot)
+		}
+	}
+	if len(res.SqlcDiffs) > 0 {
+		return exitProblems, fmt.Errorf("Go migrations change the schema outside what sqlc sees, move the DDL into .sql files")
+	}
+	return exitOK, nil
+}
+
+// compares the snapshot file against the live database
+func runDiff(ctx context.Context, pool *pgxpool.Pool, opts options, res *result) (int, error) {
+	expected, err := dbConn.ReadSnapshot(opts.snapshot)
+	if err != nil {
+		return exitFailure, err
+	}
+	live, err := dbConn.DumpSchema(ctx, pool, opts.schema)
+	if err != nil {
+		return exitFailure, err
+	}
+	res.Diffs = dbConn.DiffSnapshots(expected, live)
+	if len(res.Diffs) > 0 {
+		return exitProblems, fmt.Errorf("schema %s drifted from %s", opts.schema, opts.snapshot)
+	}
+	return exitOK, nil
+}
+
+func usageError(message string) int {
+	fmt.Fprintf(os.Stderr, "migrate: %s\n\n%s", message, usage)
+	return exitUsage
+}
+
+func printResult(opts options, res result) {
+	if opts.json {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		_ = enc.Encode(res)
+		return
+	}
+
+	for _, v := range res.Applied {
+		fmt.Printf("%s: %s\n", res.Command, v)
+	}
+	for _, f := range res.Files {
+		fmt.Printf("created: %s\n", f)
+	}
+	for _, m := range res.Migrations {
+		state := "pending"
+		if m.Applied {
+			state = "applied " + m.AppliedAt.Format(time.RFC3339)
+		}
+		fmt.Printf("%-16s %-4s %-40s %s\n", m.Version, m.Kind, m.Name, state)
+	}
+	for _, p := range res.Problems {
+		fmt.Printf("problem: %s\n", p)
+	}
+	for _, d := range res.Diffs {
+		fmt.Println(d.String())
+	}
+	for _, d := range res.SqlcDiffs {
+		fmt.Println("sqlc " + d.String())
+	}
+	if res.Error != "" {
+		fmt.Fprintf(os.Stderr, "migrate %s: %s\n", res.Command, res.Error)
+		return
+	}
+	if res.Version != "" {
+		fmt.Printf("current version: %s\n", res.Version)
+	}
+}
@@ -0,0 +1,49 @@
+// Command openapi writes the OpenAPI document of the API routes to docs/openapi.json,
+// or with -check fails when the committed document is out of date.
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"os"
+
+	"go-rest-template/internal/routes"
+)
+
+func main() {
+	out := flag.String("out", "docs/openapi.json", "output file")
+	check := flag.Bool("check", false, "fail if the output file is out of date instead of writing it")
+	flag.Parse()
+
+	doc, err := routes.OpenAPIDocument()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "openapi: %v\n", err)
+		os.Exit(1)
+	}
+	content, err := doc.Marshal()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "openapi: %v\n", err)
+		os.Exit(1)
+	}
+
+	if *check {
+		committed, err := os.ReadFile(*out)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "openapi: %v\n", err)
+			os.Exit(1)
+		}
+		if !bytes.Equal(committed, content) {
+			fmt.Fprintf(os.Stderr, "openapi: %s is out of date, run `make openapi`\n", *out)
+			os.Exit(1)
+		}
+		fmt.Printf("%s is up to date\n", *out)
+		return
+	}
+
+	if err := os.WriteFile(*out, content, 0644); err != nil {
+		fmt.Fprintf(os.Stderr, "openapi: %v\n", err)
+		os.Exit(1)
+	}
+	fmt.Printf("wrote %s\n", *out)
+}
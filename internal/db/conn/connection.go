@@ -4,21 +4,28 @@ import (
 	"context"
 	"go-rest-template/pkg/config"
 	"go-rest-template/pkg/logger"
+	"go-rest-template/pkg/tracing"
 
 	"github.com/jackc/pgx/v5/pgxpool"
 )
 
 func ConnectToDB() *pgxpool.Pool {
-	url := config.APP().DB_URL
-	cfg, err := pgxpool.ParseConfig(url)
+	pool, err := Connect(config.APP().DB_URL)
 	if err != nil {
-		logger.Panic("Invalid DB config: %v", err)
+		logger.PanicF("Unable to connect to database pool: %v", err)
 	}
 
-	pool, err := pgxpool.NewWithConfig(context.Background(), cfg)
+	return pool
+}
+
+// Connect creates a connection pool for the given database url.
+func Connect(url string) (*pgxpool.Pool, error) {
+	cfg, err := pgxpool.ParseConfig(url)
 	if err != nil {
-		logger.Panic("Unable to connect to database pool: %v", err)
+		return nil, err
 	}
+	// every query becomes a span named after its sqlc query name
+	cfg.ConnConfig.Tracer = tracing.QueryTracer{}
 
-	return pool
+	return pgxpool.NewWithConfig(context.Background(), cfg)
 }
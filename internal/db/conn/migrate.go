@@ -2,33 +2,126 @@ package db
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"errors"
 	"fmt"
 	"go-rest-template/pkg/logger"
 	"os"
 	"path/filepath"
+	"regexp"
 	"sort"
 	"strings"
+	"time"
 
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
 	"github.com/jackc/pgx/v5/pgxpool"
 )
 
+// MigrationFunc is a Go-code migration step. It runs inside the same
+// transaction as the schema_migrations bookkeeping for its version.
+type MigrationFunc func(ctx context.Context, tx pgx.Tx) error
+
 type Migration struct {
 	Version string
+	Name    string
 	UpSQL   string
 	DownSQL string
+	UpFn    MigrationFunc
+	DownFn  MigrationFunc
+}
+
+// advisory lock key used to serialize migration runs across processes
+const migrationLockKey int64 = 7283461900
+
+// registered Go-code migrations keyed by version
+var goMigrations = map[string]*Migration{}
+
+// RegisterGoMigration registers a migration implemented in Go. It is meant to be
+// called from an init function and is interleaved by version with the SQL files.
+func RegisterGoMigration(version, name string, up, down MigrationFunc) {
+	if _, exists := goMigrations[version]; exists {
+		panic(fmt.Sprintf("migration %s registered twice", version))
+	}
+	goMigrations[version] = &Migration{
+		Version: version,
+		Name:    name,
+		UpFn:    up,
+		DownFn:  down,
+	}
 }
 
-func ensureMigrationsTable(pool *pgxpool.Pool) error {
-	_, err := pool.Exec(context.Background(), `
+// applies the up step of the migration within tx
+func (m Migration) up(ctx context.Context, tx pgx.Tx) error {
+	if m.UpFn != nil {
+		return m.UpFn(ctx, tx)
+	}
+	_, err := tx.Exec(ctx, m.UpSQL)
+	return err
+}
+
+// applies the down step of the migration within tx
+func (m Migration) down(ctx context.Context, tx pgx.Tx) error {
+	if m.UpFn != nil {
+		if m.DownFn == nil {
+			return fmt.Errorf("migration %s has no down function", m.Version)
+		}
+		return m.DownFn(ctx, tx)
+	}
+	_, err := tx.Exec(ctx, m.DownSQL)
+	return err
+}
+
+func ensureMigrationsTable(ctx context.Context, conn *pgxpool.Conn) error {
+	_, err := conn.Exec(ctx, `
 		CREATE TABLE IF NOT EXISTS schema_migrations (
 			version TEXT PRIMARY KEY,
 			applied_at TIMESTAMPTZ DEFAULT now()
 		);
+		ALTER TABLE schema_migrations ADD COLUMN IF NOT EXISTS checksum TEXT;
 	`)
 	return err
 }
 
+// acquires a dedicated connection holding the migration advisory lock.
+// The returned release func unlocks and returns the connection to the pool.
+func lockMigrations(ctx context.Context, pool *pgxpool.Pool) (*pgxpool.Conn, func(), error) {
+	conn, err := pool.Acquire(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+	if _, err := conn.Exec(ctx, `SELECT pg_advisory_lock($1)`, migrationLockKey); err != nil {
+		conn.Release()
+		return nil, nil, err
+	}
+	release := func() {
+		_, _ = conn.Exec(context.Background(), `SELECT pg_advisory_unlock($1)`, migrationLockKey)
+		conn.Release()
+	}
+	if err := ensureMigrationsTable(ctx, conn); err != nil {
+		release()
+		return nil, nil, err
+	}
+	return conn, release, nil
+}
+
+// matches migration file names such as 000001_create_user_table.up.sql
+// or 20261018123045_add_user_name.down.sql
+var migrationFileRegex = regexp.MustCompile(`^(\d+)_(\w+)\.(up|down)\.sql$`)
+
+var versionRegex = regexp.MustCompile(`^\d+$`)
+
+// reports whether version a sorts before b. Versions are compared numerically so
+// that sequential (000001) and timestamp (20261018123045) versions interleave.
+func versionLess(a, b string) bool {
+	a, b = strings.TrimLeft(a, "0"), strings.TrimLeft(b, "0")
+	if len(a) != len(b) {
+		return len(a) < len(b)
+	}
+	return a < b
+}
+
 func loadMigrations(dir string) ([]Migration, error) {
 	files, err := os.ReadDir(dir)
 	if err != nil {
@@ -36,129 +129,509 @@ func loadMigrations(dir string) ([]Migration, error) {
 	}
 
 	mMap := map[string]*Migration{}
+	// directions with a file per version, an empty file is still a migration
+	found := map[string]map[string]bool{}
+	var problems []error
 	for _, f := range files {
 		name := f.Name()
-		if !strings.HasSuffix(name, ".sql") {
+		if f.IsDir() || !strings.HasSuffix(name, ".sql") {
 			continue
 		}
-		parts := strings.Split(name, ".")
-		if len(parts) < 3 {
+		match := migrationFileRegex.FindStringSubmatch(name)
+		if match == nil {
+			problems = append(problems, fmt.Errorf("%s: not a valid migration file name (<version>_<name>.up.sql|.down.sql)", name))
 			continue
 		}
-		version := strings.Split(parts[0], "_")[0]
+		version, migName, direction := match[1], match[2], match[3]
 		mig, ok := mMap[version]
 		if !ok {
-			mig = &Migration{Version: version}
+			mig = &Migration{Version: version, Name: migName}
 			mMap[version] = mig
 		}
+		if mig.Name != migName {
+			problems = append(problems, fmt.Errorf("duplicate migration version %s: %s_%s and %s_%s", version, version, mig.Name, version, migName))
+			continue
+		}
 		content, err := os.ReadFile(filepath.Join(dir, name))
 		if err != nil {
 			return nil, err
 		}
-		if strings.HasSuffix(name, ".up.sql") {
+		if found[version] == nil {
+			found[version] = map[string]bool{}
+		}
+		found[version][direction] = true
+		if direction == "up" {
 			mig.UpSQL = string(content)
-		} else if strings.HasSuffix(name, ".down.sql") {
+		} else {
 			mig.DownSQL = string(content)
 		}
 	}
 
+	for _, m := range mMap {
+		base := m.Version + "_" + m.Name
+		if !found[m.Version]["up"] {
+			problems = append(problems, fmt.Errorf("orphaned migration %s: %s.down.sql has no matching .up.sql", m.Version, base))
+		}
+		if !found[m.Version]["down"] {
+			problems = append(problems, fmt.Errorf("orphaned migration %s: %s.up.sql has no matching .down.sql", m.Version, base))
+		}
+	}
+
+	for version, gm := range goMigrations {
+		if !versionRegex.MatchString(version) {
+			problems = append(problems, fmt.Errorf("go migration %s: version must be numeric", version))
+			continue
+		}
+		for v := range mMap {
+			if strings.TrimLeft(v, "0") == strings.TrimLeft(version, "0") {
+				problems = append(problems, fmt.Errorf("duplicate migration version %s: defined both as SQL and Go", version))
+			}
+		}
+		mMap[version] = gm
+	}
+
+	if len(problems) > 0 {
+		sort.Slice(problems, func(i, j int) bool { return problems[i].Error() < problems[j].Error() })
+		return nil, errors.Join(problems...)
+	}
+
 	var migrations []Migration
 	for _, m := range mMap {
 		migrations = append(migrations, *m)
 	}
 	sort.Slice(migrations, func(i, j int) bool {
-		return migrations[i].Version < migrations[j].Version
+		return versionLess(migrations[i].Version, migrations[j].Version)
 	})
+	for i := 1; i < len(migrations); i++ {
+		prev, cur := migrations[i-1], migrations[i]
+		if !versionLess(prev.Version, cur.Version) {
+			return nil, fmt.Errorf("duplicate migration version: %s_%s and %s_%s", prev.Version, prev.Name, cur.Version, cur.Name)
+		}
+	}
 	return migrations, nil
 }
 
-func RunMigrations(pool *pgxpool.Pool, dir string) error {
-	ctx := context.Background()
-	if err := ensureMigrationsTable(pool); err != nil {
+// ValidateMigrations loads the migrations in dir and reports duplicate versions,
+// orphaned up/down files and malformed file names.
+func ValidateMigrations(dir string) error {
+	_, err := loadMigrations(dir)
+	return err
+}
+
+// runs fn in a transaction on conn, committing only if fn succeeds
+func inTx(ctx context.Context, conn *pgxpool.Conn, fn func(tx pgx.Tx) error) error {
+	tx, err := conn.Begin(ctx)
+	if err != nil {
 		return err
 	}
-	migs, err := loadMigrations(dir)
-	if err != nil {
+	defer tx.Rollback(ctx)
+	if err := fn(tx); err != nil {
 		return err
 	}
+	return tx.Commit(ctx)
+}
+
+// MigrationState describes a known migration and whether it is applied.
+type MigrationState struct {
+	Version   string     `json:"version"`
+	Name      string     `json:"name"`
+	Kind      string     `json:"kind"`
+	Applied   bool       `json:"applied"`
+	AppliedAt *time.Time `json:"applied_at,omitempty"`
+}
+
+type appliedMigration struct {
+	appliedAt time.Time
+	checksum  *string
+}
+
+// returns the sha256 of the SQL source, or nil for Go migrations
+func (m Migration) checksum() *string {
+	if m.UpFn != nil {
+		return nil
+	}
+	sum := sha256.Sum256([]byte(m.UpSQL))
+	hexSum := hex.EncodeToString(sum[:])
+	return &hexSum
+}
+
+func (m Migration) kind() string {
+	if m.UpFn != nil {
+		return "go"
+	}
+	return "sql"
+}
 
-	applied := map[string]bool{}
-	rows, err := pool.Query(ctx, `SELECT version FROM schema_migrations`)
+func readApplied(ctx context.Context, conn *pgxpool.Conn) (map[string]appliedMigration, error) {
+	rows, err := conn.Query(ctx, `SELECT version, applied_at, checksum FROM schema_migrations`)
 	if err != nil {
-		return err
+		return nil, err
 	}
 	defer rows.Close()
+
+	applied := map[string]appliedMigration{}
 	for rows.Next() {
 		var v string
-		_ = rows.Scan(&v)
-		applied[v] = true
+		var a appliedMigration
+		if err := rows.Scan(&v, &a.appliedAt, &a.checksum); err != nil {
+			return nil, err
+		}
+		applied[v] = a
 	}
+	return applied, rows.Err()
+}
 
-	for _, m := range migs {
-		if applied[m.Version] {
-			continue
-		}
-		logger.Info("Applying migration: %s", m.Version)
-		if _, err := pool.Exec(ctx, m.UpSQL); err != nil {
-			return fmt.Errorf("error applying %s: %w", m.Version, err)
+// reads the applied migrations without taking the lock or creating the
+// bookkeeping table, for commands that must not change the database. A missing
+// table means nothing is applied, a table from before checksums has none.
+func readAppliedReadOnly(ctx context.Context, pool *pgxpool.Pool) (map[string]appliedMigration, error) {
+	conn, err := pool.Acquire(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Release()
+
+	applied, err := readApplied(ctx, conn)
+	var pgErr *pgconn.PgError
+	if !errors.As(err, &pgErr) {
+		return applied, err
+	}
+	switch pgErr.Code {
+	case "42P01": // undefined_table
+		return map[string]appliedMigration{}, nil
+	case "42703": // undefined_column
+		rows, err := conn.Query(ctx, `SELECT version, applied_at FROM schema_migrations`)
+		if err != nil {
+			return nil, err
 		}
-		if _, err := pool.Exec(ctx, `INSERT INTO schema_migrations (version) VALUES ($1)`, m.Version); err != nil {
-			return err
+		defer rows.Close()
+		applied := map[string]appliedMigration{}
+		for rows.Next() {
+			var v string
+			var a appliedMigration
+			if err := rows.Scan(&v, &a.appliedAt); err != nil {
+				return nil, err
+			}
+			applied[v] = a
 		}
+		return applied, rows.Err()
 	}
-	return nil
+	return nil, err
 }
 
-func RollbackMigrations(pool *pgxpool.Pool, dir string, n int) error {
-	ctx := context.Background()
-	if err := ensureMigrationsTable(pool); err != nil {
+func applyUp(ctx context.Context, conn *pgxpool.Conn, m Migration) error {
+	return inTx(ctx, conn, func(tx pgx.Tx) error {
+		if err := m.up(ctx, tx); err != nil {
+			return fmt.Errorf("error applying %s: %w", m.Version, err)
+		}
+		_, err := tx.Exec(ctx, `INSERT INTO schema_migrations (version, checksum) VALUES ($1, $2)`, m.Version, m.checksum())
+		return err
+	})
+}
+
+func applyDown(ctx context.Context, conn *pgxpool.Conn, m Migration) error {
+	return inTx(ctx, conn, func(tx pgx.Tx) error {
+		if err := m.down(ctx, tx); err != nil {
+			return fmt.Errorf("rollback error %s: %w", m.Version, err)
+		}
+		_, err := tx.Exec(ctx, `DELETE FROM schema_migrations WHERE version = $1`, m.Version)
 		return err
+	})
+}
+
+func findMigration(migs []Migration, version string) (Migration, bool) {
+	idx := sort.Search(len(migs), func(i int) bool { return !versionLess(migs[i].Version, version) })
+	if idx == len(migs) || migs[idx].Version != version {
+		return Migration{}, false
 	}
+	return migs[idx], true
+}
+
+// MigrateUp applies every pending migration and returns the applied versions.
+func MigrateUp(ctx context.Context, pool *pgxpool.Pool, dir string) ([]string, error) {
 	migs, err := loadMigrations(dir)
 	if err != nil {
-		return err
+		return nil, err
 	}
+	conn, release, err := lockMigrations(ctx, pool)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
 
-	rows, err := pool.Query(ctx, `SELECT version FROM schema_migrations ORDER BY applied_at DESC LIMIT $1`, n)
+	applied, err := readApplied(ctx, conn)
 	if err != nil {
-		return err
+		return nil, err
 	}
-	defer rows.Close()
 
-	var toRollback []string
-	for rows.Next() {
-		var v string
-		_ = rows.Scan(&v)
-		toRollback = append(toRollback, v)
+	var done []string
+	for _, m := range migs {
+		if _, ok := applied[m.Version]; ok {
+			continue
+		}
+		if err := applyUp(ctx, conn, m); err != nil {
+			return done, err
+		}
+		done = append(done, m.Version)
+	}
+	return done, nil
+}
+
+// MigrateDown rolls back the last n applied migrations and returns their versions.
+func MigrateDown(ctx context.Context, pool *pgxpool.Pool, dir string, n int) ([]string, error) {
+	migs, err := loadMigrations(dir)
+	if err != nil {
+		return nil, err
 	}
+	conn, release, err := lockMigrations(ctx, pool)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
 
+	rows, err := conn.Query(ctx, `SELECT version FROM schema_migrations ORDER BY applied_at DESC, length(ltrim(version, '0')) DESC, ltrim(version, '0') DESC LIMIT $1`, n)
+	if err != nil {
+		return nil, err
+	}
+	toRollback, err := pgx.CollectRows(rows, pgx.RowTo[string])
+	if err != nil {
+		return nil, err
+	}
 	if len(toRollback) == 0 {
-		return errors.New("no migrations to rollback")
+		return nil, errors.New("no migrations to rollback")
 	}
 
+	var done []string
 	for _, rev := range toRollback {
+		m, ok := findMigration(migs, rev)
+		if !ok {
+			return done, fmt.Errorf("migration %s is applied but was not found", rev)
+		}
+		if err := applyDown(ctx, conn, m); err != nil {
+			return done, err
+		}
+		done = append(done, m.Version)
+	}
+	return done, nil
+}
+
+// MigrateTo moves the schema to the given version, applying pending migrations
+// up to it and rolling back applied ones above it. Version "0" rolls back everything.
+func MigrateTo(ctx context.Context, pool *pgxpool.Pool, dir string, version string) ([]string, error) {
+	migs, err := loadMigrations(dir)
+	if err != nil {
+		return nil, err
+	}
+	if _, ok := findMigration(migs, version); !ok && version != "0" {
+		return nil, fmt.Errorf("unknown migration version %s", version)
+	}
+	conn, release, err := lockMigrations(ctx, pool)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+
+	applied, err := readApplied(ctx, conn)
+	if err != nil {
+		return nil, err
+	}
+	for v := range applied {
+		if _, ok := findMigration(migs, v); !ok {
+			return nil, fmt.Errorf("migration %s is applied but was not found", v)
+		}
+	}
+
+	var done []string
+	for i := len(migs) - 1; i >= 0; i-- {
+		m := migs[i]
+		if _, ok := applied[m.Version]; !ok || !versionLess(version, m.Version) {
+			continue
+		}
+		if err := applyDown(ctx, conn, m); err != nil {
+			return done, err
+		}
+		done = append(done, m.Version)
+	}
+	for _, m := range migs {
+		if _, ok := applied[m.Version]; ok || versionLess(version, m.Version) {
+			continue
+		}
+		if err := applyUp(ctx, conn, m); err != nil {
+			return done, err
+		}
+		done = append(done, m.Version)
+	}
+	return done, nil
+}
+
+// ForceVersion rewrites the bookkeeping so that exactly the migrations up to
+// version are recorded as applied, without running any of them.
+func ForceVersion(ctx context.Context, pool *pgxpool.Pool, dir string, version string) error {
+	migs, err := loadMigrations(dir)
+	if err != nil {
+		return err
+	}
+	if _, ok := findMigration(migs, version); !ok && version != "0" {
+		return fmt.Errorf("unknown migration version %s", version)
+	}
+	conn, release, err := lockMigrations(ctx, pool)
+	if err != nil {
+		return err
+	}
+	defer release()
+
+	return inTx(ctx, conn, func(tx pgx.Tx) error {
+		if _, err := tx.Exec(ctx, `DELETE FROM schema_migrations`); err != nil {
+			return err
+		}
 		for _, m := range migs {
-			if m.Version == rev {
-				logger.Info("Rolling back migration: %s", m.Version)
-				if _, err := pool.Exec(ctx, m.DownSQL); err != nil {
-					return fmt.Errorf("rollback error %s: %w", m.Version, err)
-				}
-				if _, err := pool.Exec(ctx, `DELETE FROM schema_migrations WHERE version = $1`, m.Version); err != nil {
-					return err
-				}
+			if versionLess(version, m.Version) {
 				break
 			}
+			if _, err := tx.Exec(ctx, `INSERT INTO schema_migrations (version, checksum) VALUES ($1, $2)`, m.Version, m.checksum()); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// GetMigrationStatus lists every known or applied migration in version order.
+// It only reads the database.
+func GetMigrationStatus(ctx context.Context, pool *pgxpool.Pool, dir string) ([]MigrationState, error) {
+	migs, err := loadMigrations(dir)
+	if err != nil {
+		return nil, err
+	}
+	applied, err := readAppliedReadOnly(ctx, pool)
+	if err != nil {
+		return nil, err
+	}
+
+	states := make([]MigrationState, 0, len(migs))
+	for _, m := range migs {
+		s := MigrationState{Version: m.Version, Name: m.Name, Kind: m.kind()}
+		if a, ok := applied[m.Version]; ok {
+			s.Applied = true
+			s.AppliedAt = &a.appliedAt
+		}
+		states = append(states, s)
+	}
+	for v, a := range applied {
+		if _, ok := findMigration(migs, v); !ok {
+			s := MigrationState{Version: v, Kind: "missing", Applied: true, AppliedAt: &a.appliedAt}
+			states = append(states, s)
+		}
+	}
+	sort.Slice(states, func(i, j int) bool {
+		return versionLess(states[i].Version, states[j].Version)
+	})
+	return states, nil
+}
+
+// LocalVersions returns the versions of the SQL migrations in dir and the
+// registered Go migrations, in order.
+func LocalVersions(dir string) ([]string, error) {
+	migs, err := loadMigrations(dir)
+	if err != nil {
+		return nil, err
+	}
+	versions := make([]string, len(migs))
+	for i, m := range migs {
+		versions[i] = m.Version
+	}
+	return versions, nil
+}
+
+// PendingMigrations returns the versions, as returned by LocalVersions, that are not
+// applied yet. Unlike GetMigrationStatus it takes no lock, creates nothing and reads
+// no files, so it is cheap enough for readiness checks.
+func PendingMigrations(ctx context.Context, pool *pgxpool.Pool, versions []string) ([]string, error) {
+	applied, err := readAppliedReadOnly(ctx, pool)
+	if err != nil {
+		return nil, err
+	}
+
+	var pending []string
+	for _, v := range versions {
+		if _, ok := applied[v]; !ok {
+			pending = append(pending, v)
+		}
+	}
+	return pending, nil
+}
+
+// VerifyMigrations checks the applied migrations against the local set and
+// returns a description of every problem found: applied versions missing
+// locally, SQL files changed after being applied, and pending migrations
+// older than the latest applied one. It only reads the database.
+func VerifyMigrations(ctx context.Context, pool *pgxpool.Pool, dir string) ([]string, error) {
+	migs, err := loadMigrations(dir)
+	if err != nil {
+		return nil, err
+	}
+	applied, err := readAppliedReadOnly(ctx, pool)
+	if err != nil {
+		return nil, err
+	}
+
+	var problems []string
+	latest := ""
+	for v := range applied {
+		if _, ok := findMigration(migs, v); !ok {
+			problems = append(problems, fmt.Sprintf("%s: applied but not found locally", v))
+		}
+		if latest == "" || versionLess(latest, v) {
+			latest = v
+		}
+	}
+	for _, m := range migs {
+		a, ok := applied[m.Version]
+		if !ok {
+			if versionLess(m.Version, latest) {
+				problems = append(problems, fmt.Sprintf("%s: pending but older than applied version %s", m.Version, latest))
+			}
+			continue
+		}
+		sum := m.checksum()
+		if sum != nil && a.checksum != nil && *sum != *a.checksum {
+			problems = append(problems, fmt.Sprintf("%s: changed after it was applied", m.Version))
 		}
 	}
-	return nil
+	sort.Strings(problems)
+	return problems, nil
+}
+
+func RunMigrations(pool *pgxpool.Pool, dir string) error {
+	done, err := MigrateUp(context.Background(), pool, dir)
+	for _, v := range done {
+		logger.InfoF("Applied migration: %s", v)
+	}
+	return err
+}
+
+func RollbackMigrations(pool *pgxpool.Pool, dir string, n int) error {
+	done, err := MigrateDown(context.Background(), pool, dir, n)
+	for _, v := range done {
+		logger.InfoF("Rolled back migration: %s", v)
+	}
+	return err
 }
 
-func GetMigrationVersion(pool *pgxpool.Pool) (string, bool, error) {
-	ctx := context.Background()
+// GetMigrationVersion returns the highest applied version, "0" when none is applied
+// or the schema_migrations table doesn't exist yet. It orders by version rather than
+// applied_at because forced rows all share one timestamp.
+func GetMigrationVersion(ctx context.Context, pool *pgxpool.Pool) (string, bool, error) {
 	var version string
-	err := pool.QueryRow(ctx, `SELECT version FROM schema_migrations ORDER BY applied_at DESC LIMIT 1`).Scan(&version)
-	if err != nil {
+	err := pool.QueryRow(ctx, `SELECT version FROM schema_migrations ORDER BY length(ltrim(version, '0')) DESC, ltrim(version, '0') DESC LIMIT 1`).Scan(&version)
+	var pgErr *pgconn.PgError
+	switch {
+	case errors.Is(err, pgx.ErrNoRows):
+		return "0", false, nil
+	case errors.As(err, &pgErr) && pgErr.Code == "42P01": // undefined_table
 		return "0", false, nil
+	case err != nil:
+		return "", false, err
 	}
 	return version, false, nil
 }
@@ -0,0 +1,48 @@
+package db
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestVersionLess(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want bool
+	}{
+		{"000001", "000002", true},
+		{"000002", "000001", false},
+		{"000001", "000001", false},
+		{"000009", "000010", true},
+		{"1", "000002", true},
+		{"000010", "2", false},
+		// sequential versions sort before timestamps
+		{"000123", "20261018123045", true},
+		{"20261018123045", "000123", false},
+		{"20261018123045", "20261018123046", true},
+		{"20261018123045", "20271018123045", true},
+		{"0", "000001", true},
+	}
+	for _, tt := range tests {
+		if got := versionLess(tt.a, tt.b); got != tt.want {
+			t.Errorf("versionLess(%q, %q) = %v, want %v", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
+
+func TestVersionLessSorts(t *testing.T) {
+	versions := []string{"20261018123045", "000010", "000002", "20250101000000", "000001"}
+	slices.SortFunc(versions, func(a, b string) int {
+		switch {
+		case versionLess(a, b):
+			return -1
+		case versionLess(b, a):
+			return 1
+		}
+		return 0
+	})
+	want := []string{"000001", "000002", "000010", "20250101000000", "20261018123045"}
+	if !slices.Equal(versions, want) {
+		t.Errorf("sorted %v, want %v", versions, want)
+	}
+}
@@ -0,0 +1,343 @@
+package db
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// SchemaSnapshot is a normalized dump of the catalog of a single schema.
+type SchemaSnapshot struct {
+	Tables []TableSnapshot `json:"tables"`
+}
+
+type TableSnapshot struct {
+	Name        string               `json:"name"`
+	Columns     []ColumnSnapshot     `json:"columns"`
+	Indexes     []IndexSnapshot      `json:"indexes"`
+	Constraints []ConstraintSnapshot `json:"constraints"`
+}
+
+type ColumnSnapshot struct {
+	Name     string  `json:"name"`
+	Type     string  `json:"type"`
+	Nullable bool    `json:"nullable"`
+	Default  *string `json:"default,omitempty"`
+}
+
+type IndexSnapshot struct {
+	Name       string `json:"name"`
+	Definition string `json:"definition"`
+}
+
+type ConstraintSnapshot struct {
+	Name       string `json:"name"`
+	Type       string `json:"type"`
+	Definition string `json:"definition"`
+}
+
+// SchemaDiff is a single difference between an expected and an actual snapshot.
+type SchemaDiff struct {
+	Change   string `json:"change"` // missing, extra or changed
+	Object   string `json:"object"` // table, column, index or constraint
+	Name     string `json:"name"`
+	Expected string `json:"expected,omitempty"`
+	Actual   string `json:"actual,omitempty"`
+}
+
+func (d SchemaDiff) String() string {
+	switch d.Change {
+	case "missing":
+		return fmt.Sprintf("- %s %s: %s", d.Object, d.Name, d.Expected)
+	case "extra":
+		return fmt.Sprintf("+ %s %s: %s", d.Object, d.Name, d.Actual)
+	default:
+		return fmt.Sprintf("~ %s %s: expected %s, got %s", d.Object, d.Name, d.Expected, d.Actual)
+	}
+}
+
+// bookkeeping tables that are not part of the application schema
+var snapshotIgnoredTables = map[string]bool{
+	"schema_migrations": true,
+	"schema_seeds":      true,
+}
+
+var constraintTypes = map[string]string{
+	"c": "check",
+	"f": "foreign_key",
+	"p": "primary_key",
+	"u": "unique",
+	"t": "trigger",
+	"x": "exclusion",
+}
+
+// DumpSchema reads the tables, columns, indexes and constraints of the given
+// schema. Schema qualifiers are stripped so dumps of different schemas compare equal.
+func DumpSchema(ctx context.Context, pool *pgxpool.Pool, schema string) (*SchemaSnapshot, error) {
+	tables := map[string]*TableSnapshot{}
+	var order []string
+	table := func(name string) *TableSnapshot {
+		t, ok := tables[name]
+		if !ok {
+			t = &TableSnapshot{Name: name, Columns: []ColumnSnapshot{}, Indexes: []IndexSnapshot{}, Constraints: []ConstraintSnapshot{}}
+			tables[name] = t
+			order = append(order, name)
+		}
+		return t
+	}
+	normalize := func(def string) string {
+		def = strings.ReplaceAll(def, `"`+schema+`".`, "")
+		return strings.ReplaceAll(def, schema+".", "")
+	}
+
+	rows, err := pool.Query(ctx, `
+		SELECT c.relname, a.attname, format_type(a.atttypid, a.atttypmod), NOT a.attnotnull, pg_get_expr(d.adbin, d.adrelid)
+		FROM pg_attribute a
+		JOIN pg_class c ON c.oid = a.attrelid
+		JOIN pg_namespace n ON n.oid = c.relnamespace
+		LEFT JOIN pg_attrdef d ON d.adrelid = a.attrelid AND d.adnum = a.attnum
+		WHERE n.nspname = $1 AND c.relkind IN ('r', 'p') AND a.attnum > 0 AND NOT a.attisdropped
+		ORDER BY c.relname, a.attnum`, schema)
+	if err != nil {
+		return nil, err
+	}
+	err = forEachRow(rows, func() error {
+		var tbl string
+		var col ColumnSnapshot
+		if err := rows.Scan(&tbl, &col.Name, &col.Type, &col.Nullable, &col.Default); err != nil {
+			return err
+		}
+		if col.Default != nil {
+			def := normalize(*col.Default)
+			col.Default = &def
+		}
+		t := table(tbl)
+		t.Columns = append(t.Columns, col)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err = pool.Query(ctx, `
+		SELECT t.relname, i.relname, pg_get_indexdef(i.oid)
+		FROM pg_index x
+		JOIN pg_class i ON i.oid = x.indexrelid
+		JOIN pg_class t ON t.oid = x.indrelid
+		JOIN pg_namespace n ON n.oid = t.relnamespace
+		WHERE n.nspname = $1
+		ORDER BY t.relname, i.relname`, schema)
+	if err != nil {
+		return nil, err
+	}
+	err = forEachRow(rows, func() error {
+		var tbl string
+		var idx IndexSnapshot
+		if err := rows.Scan(&tbl, &idx.Name, &idx.Definition); err != nil {
+			return err
+		}
+		idx.Definition = normalize(idx.Definition)
+		t := table(tbl)
+		t.Indexes = append(t.Indexes, idx)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err = pool.Query(ctx, `
+		SELECT t.relname, con.conname, con.contype::text, pg_get_constraintdef(con.oid)
+		FROM pg_constraint con
+		JOIN pg_class t ON t.oid = con.conrelid
+		JOIN pg_namespace n ON n.oid = t.relnamespace
+		WHERE n.nspname = $1 AND con.contype <> 'n' -- not-null is part of the column
+		ORDER BY t.relname, con.conname`, schema)
+	if err != nil {
+		return nil, err
+	}
+	err = forEachRow(rows, func() error {
+		var tbl string
+		var con ConstraintSnapshot
+		if err := rows.Scan(&tbl, &con.Name, &con.Type, &con.Definition); err != nil {
+			return err
+		}
+		if name, ok := constraintTypes[con.Type]; ok {
+			con.Type = name
+		}
+		con.Definition = normalize(con.Definition)
+		t := table(tbl)
+		t.Constraints = append(t.Constraints, con)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	sort.Strings(order)
+	snap := &SchemaSnapshot{Tables: []TableSnapshot{}}
+	for _, name := range order {
+		if snapshotIgnoredTables[name] {
+			continue
+		}
+		snap.Tables = append(snap.Tables, *tables[name])
+	}
+	return snap, nil
+}
+
+func forEachRow(rows pgx.Rows, fn func() error) error {
+	defer rows.Close()
+	for rows.Next() {
+		if err := fn(); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
+
+// SnapshotFromMigrations applies every migration in dir to a temporary schema
+// of the database behind pool, dumps it and drops the schema again.
+func SnapshotFromMigrations(ctx context.Context, pool *pgxpool.Pool, dir string) (*SchemaSnapshot, error) {
+	return snapshotInScratch(ctx, pool, func(scratchPool *pgxpool.Pool) error {
+		_, err := MigrateUp(ctx, scratchPool, dir)
+		return err
+	})
+}
+
+// SnapshotForSqlc dumps the schema sqlc generates code from: only the .up.sql
+// files of dir, applied in version order. Go migrations are invisible to sqlc,
+// so a difference from SnapshotFromMigrations means generated code can be wrong.
+func SnapshotForSqlc(ctx context.Context, pool *pgxpool.Pool, dir string) (*SchemaSnapshot, error) {
+	migs, err := loadMigrations(dir)
+	if err != nil {
+		return nil, err
+	}
+	return snapshotInScratch(ctx, pool, func(scratchPool *pgxpool.Pool) error {
+		for _, m := range migs {
+			if m.UpFn != nil {
+				continue
+			}
+			if _, err := scratchPool.Exec(ctx, m.UpSQL); err != nil {
+				return fmt.Errorf("error applying %s: %w", m.Version, err)
+			}
+		}
+		return nil
+	})
+}
+
+// runs apply against a temporary schema, dumps it and drops the schema again.
+// public stays on the search_path so extension functions and types resolve.
+func snapshotInScratch(ctx context.Context, pool *pgxpool.Pool, apply func(scratchPool *pgxpool.Pool) error) (*SchemaSnapshot, error) {
+	scratch := fmt.Sprintf("schema_snapshot_%d", time.Now().UnixNano())
+	if _, err := pool.Exec(ctx, `CREATE SCHEMA `+pgx.Identifier{scratch}.Sanitize()); err != nil {
+		return nil, err
+	}
+	defer pool.Exec(context.Background(), `DROP SCHEMA IF EXISTS `+pgx.Identifier{scratch}.Sanitize()+` CASCADE`)
+
+	cfg := pool.Config()
+	cfg.ConnConfig.RuntimeParams["search_path"] = pgx.Identifier{scratch}.Sanitize() + ", public"
+	scratchPool, err := pgxpool.NewWithConfig(ctx, cfg)
+	if err != nil {
+		return nil, err
+	}
+	defer scratchPool.Close()
+
+	if err := apply(scratchPool); err != nil {
+		return nil, err
+	}
+	return DumpSchema(ctx, scratchPool, scratch)
+}
+
+// ReadSnapshot loads a snapshot file written by WriteSnapshot.
+func ReadSnapshot(path string) (*SchemaSnapshot, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var snap SchemaSnapshot
+	if err := json.Unmarshal(content, &snap); err != nil {
+		return nil, fmt.Errorf("invalid snapshot %s: %w", path, err)
+	}
+	return &snap, nil
+}
+
+// WriteSnapshot writes the snapshot as indented JSON so it diffs well in review.
+func WriteSnapshot(path string, snap *SchemaSnapshot) error {
+	content, err := json.MarshalIndent(snap, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(content, '\n'), 0644)
+}
+
+// DiffSnapshots reports how actual differs from expected.
+func DiffSnapshots(expected, actual *SchemaSnapshot) []SchemaDiff {
+	var diffs []SchemaDiff
+	diff := func(object, name, want, got string) {
+		switch {
+		case got == "":
+			diffs = append(diffs, SchemaDiff{Change: "missing", Object: object, Name: name, Expected: want})
+		case want == "":
+			diffs = append(diffs, SchemaDiff{Change: "extra", Object: object, Name: name, Actual: got})
+		case want != got:
+			diffs = append(diffs, SchemaDiff{Change: "changed", Object: object, Name: name, Expected: want, Actual: got})
+		}
+	}
+
+	want, got := snapshotObjects(expected), snapshotObjects(actual)
+	keys := make([]snapshotKey, 0, len(want)+len(got))
+	for k := range want {
+		keys = append(keys, k)
+	}
+	for k := range got {
+		if _, ok := want[k]; !ok {
+			keys = append(keys, k)
+		}
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i].name != keys[j].name {
+			return keys[i].name < keys[j].name
+		}
+		return keys[i].object < keys[j].object
+	})
+	for _, k := range keys {
+		diff(k.object, k.name, want[k], got[k])
+	}
+	return diffs
+}
+
+type snapshotKey struct {
+	object string
+	name   string
+}
+
+// flattens a snapshot into comparable one-line descriptions keyed by object
+func snapshotObjects(snap *SchemaSnapshot) map[snapshotKey]string {
+	objects := map[snapshotKey]string{}
+	for _, t := range snap.Tables {
+		objects[snapshotKey{"table", t.Name}] = "table " + t.Name
+		for _, c := range t.Columns {
+			desc := c.Type
+			if !c.Nullable {
+				desc += " NOT NULL"
+			}
+			if c.Default != nil {
+				desc += " DEFAULT " + *c.Default
+			}
+			objects[snapshotKey{"column", t.Name + "." + c.Name}] = desc
+		}
+		for _, i := range t.Indexes {
+			objects[snapshotKey{"index", t.Name + "." + i.Name}] = i.Definition
+		}
+		for _, c := range t.Constraints {
+			objects[snapshotKey{"constraint", t.Name + "." + c.Name}] = c.Type + " " + c.Definition
+		}
+	}
+	return objects
+}
@@ -0,0 +1,16 @@
+// Package migrations holds database migrations written in Go, for changes that
+// are awkward to express in plain SQL (data backfills, re-hashing, etc).
+//
+// Each migration registers itself from an init function and is applied in
+// version order together with the .up.sql/.down.sql files in schema/migrations:
+//
+//	func init() {
+//		dbConn.RegisterGoMigration("000002", "backfill_user_emails",
+//			func(ctx context.Context, tx pgx.Tx) error {
+//				_, err := tx.Exec(ctx, `UPDATE users SET email = lower(email)`)
+//				return err
+//			},
+//			func(ctx context.Context, tx pgx.Tx) error { return nil },
+//		)
+//	}
+package migrations
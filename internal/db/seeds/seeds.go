@@ -0,0 +1,155 @@
+// Package seeds populates a database with sample data for development, tests
+// and demos. Seeds are either SQL files in schema/seeds/<env>/ or Go seeders
+// registered with Register, and every seed is applied at most once per database.
+package seeds
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"go-rest-template/internal/db"
+	"go-rest-template/pkg/config"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// environments a seed can be scoped to
+const (
+	EnvDev  = "dev"
+	EnvTest = "test"
+	EnvDemo = "demo"
+)
+
+var Envs = []string{EnvDev, EnvTest, EnvDemo}
+
+// ErrProduction is returned by Run when GO_ENV is production and force is not set
+var ErrProduction = errors.New("refusing to seed with GO_ENV=production, the seeds contain well-known passwords")
+
+// Seeder is a seed implemented in Go with the sqlc queries.
+type Seeder struct {
+	Name string
+	Envs []string
+	Run  func(ctx context.Context, q *db.Queries) error
+}
+
+type seed struct {
+	name string
+	sql  string
+	run  func(ctx context.Context, q *db.Queries) error
+}
+
+var seeders = map[string]Seeder{}
+
+// Register adds a Go seeder, it is meant to be called from an init function.
+func Register(s Seeder) {
+	if _, exists := seeders[s.Name]; exists {
+		panic(fmt.Sprintf("seeder %s registered twice", s.Name))
+	}
+	seeders[s.Name] = s
+}
+
+func ensureSeedsTable(ctx context.Context, conn *pgxpool.Conn) error {
+	// seeds are recorded by their env/name key
+	_, err := conn.Exec(ctx, `
+		CREATE TABLE IF NOT EXISTS schema_seeds (
+			name TEXT PRIMARY KEY,
+			env TEXT NOT NULL,
+			applied_at TIMESTAMPTZ DEFAULT now()
+		)
+	`)
+	return err
+}
+
+// loads the SQL seeds in dir/env and the Go seeders for env, sorted by name.
+// Both are keyed env/name so a seed applied in one environment is still applied
+// when the same database is seeded for another.
+func loadSeeds(dir string, env string) ([]seed, error) {
+	if !slices.Contains(Envs, env) {
+		return nil, fmt.Errorf("unknown seed environment %q (%s)", env, strings.Join(Envs, ", "))
+	}
+
+	var list []seed
+	envDir := filepath.Join(dir, env)
+	files, err := os.ReadDir(envDir)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+	for _, f := range files {
+		if f.IsDir() || !strings.HasSuffix(f.Name(), ".sql") {
+			continue
+		}
+		content, err := os.ReadFile(filepath.Join(envDir, f.Name()))
+		if err != nil {
+			return nil, err
+		}
+		list = append(list, seed{name: env + "/" + strings.TrimSuffix(f.Name(), ".sql"), sql: string(content)})
+	}
+
+	for _, s := range seeders {
+		if slices.Contains(s.Envs, env) {
+			list = append(list, seed{name: env + "/" + s.Name, run: s.Run})
+		}
+	}
+
+	sort.Slice(list, func(i, j int) bool { return list[i].name < list[j].name })
+	return list, nil
+}
+
+// Run applies every seed for env that has not been applied yet and returns their names.
+// It refuses to run in production unless force is set.
+func Run(ctx context.Context, pool *pgxpool.Pool, dir string, env string, force bool) ([]string, error) {
+	if config.APP().GO_ENV == "production" && !force {
+		return nil, ErrProduction
+	}
+	list, err := loadSeeds(dir, env)
+	if err != nil {
+		return nil, err
+	}
+
+	conn, err := pool.Acquire(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Release()
+	if err := ensureSeedsTable(ctx, conn); err != nil {
+		return nil, err
+	}
+
+	rows, err := conn.Query(ctx, `SELECT name FROM schema_seeds`)
+	if err != nil {
+		return nil, err
+	}
+	names, err := pgx.CollectRows(rows, pgx.RowTo[string])
+	if err != nil {
+		return nil, err
+	}
+
+	var done []string
+	for _, s := range list {
+		if slices.Contains(names, s.name) {
+			continue
+		}
+		err := pgx.BeginFunc(ctx, conn, func(tx pgx.Tx) error {
+			if s.run != nil {
+				if err := s.run(ctx, db.New(tx)); err != nil {
+					return err
+				}
+			} else if _, err := tx.Exec(ctx, s.sql); err != nil {
+				return err
+			}
+			_, err := tx.Exec(ctx, `INSERT INTO schema_seeds (name, env) VALUES ($1, $2)`, s.name, env)
+			return err
+		})
+		if err != nil {
+			return done, fmt.Errorf("error seeding %s: %w", s.name, err)
+		}
+		done = append(done, s.name)
+	}
+	return done, nil
+}
@@ -0,0 +1,49 @@
+package seeds
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+func TestLoadSeeds(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{"dev/002_posts.sql", "dev/001_settings.sql", "dev/notes.txt", "demo/001_settings.sql"} {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte("SELECT 1;"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		env  string
+		want []string
+	}{
+		// SQL and Go seeds share the env/name key scheme, sorted by name
+		{EnvDev, []string{"dev/001_settings", "dev/002_posts", "dev/users"}},
+		{EnvDemo, []string{"demo/001_settings", "demo/users"}},
+		// no directory for the environment, only the Go seeders
+		{EnvTest, []string{"test/users"}},
+	}
+	for _, tt := range tests {
+		list, err := loadSeeds(dir, tt.env)
+		if err != nil {
+			t.Fatalf("loadSeeds(%s): %v", tt.env, err)
+		}
+		var names []string
+		for _, s := range list {
+			names = append(names, s.name)
+		}
+		if !slices.Equal(names, tt.want) {
+			t.Errorf("loadSeeds(%s) = %v, want %v", tt.env, names, tt.want)
+		}
+	}
+
+	if _, err := loadSeeds(dir, "staging"); err == nil {
+		t.Error("loadSeeds accepted an unknown environment")
+	}
+}
@@ -0,0 +1,48 @@
+package seeds
+
+import (
+	"context"
+	"errors"
+	"go-rest-template/internal/db"
+	"go-rest-template/pkg"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// password shared by every seeded user
+const seedUserPassword = "password123"
+
+var seedUserEmails = []string{
+	"admin@example.com",
+	"jane@example.com",
+	"john@example.com",
+}
+
+func init() {
+	Register(Seeder{
+		Name: "users",
+		Envs: []string{EnvDev, EnvTest, EnvDemo},
+		Run:  seedUsers,
+	})
+}
+
+// creates the sample users, skipping the ones that already exist
+func seedUsers(ctx context.Context, q *db.Queries) error {
+	hash, err := pkg.GenerateHash(ctx, seedUserPassword)
+	if err != nil {
+		return err
+	}
+	for _, email := range seedUserEmails {
+		_, err := q.GetUserByEmail(ctx, email)
+		if err == nil {
+			continue
+		}
+		if !errors.Is(err, pgx.ErrNoRows) {
+			return err
+		}
+		if _, err := q.CreateUser(ctx, db.CreateUserParams{Email: email, PasswordHash: hash}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -1,5 +1,12 @@
 package dto
 
+import (
+	"context"
+	"errors"
+	"go-rest-template/internal/db"
+	"go-rest-template/pkg"
+)
+
 type User_Signup_Request struct {
 	Email    string `json:"email" validate:"required,email"`
 	Password string `json:"password" validate:"required,min=6,max=16"`
@@ -9,3 +16,37 @@ type User_Login_Request struct {
 	Email    string `json:"email" validate:"required,email"`
 	Password string `json:"password" validate:"required"`
 }
+
+// UserLookup finds users for the database-backed validation rules
+type UserLookup interface {
+	FindByEmail(ctx context.Context, email string) (db.User, error)
+}
+
+func init() {
+	for locale, text := range map[string]string{
+		"en": "Email is already registered",
+		"es": "El correo electrónico ya está registrado",
+		"fr": "Cet e-mail est déjà enregistré",
+	} {
+		if err := pkg.AddValidationMessage(locale, "email_taken", text); err != nil {
+			panic(err)
+		}
+	}
+}
+
+var errNoUserLookup = errors.New("UserLookup not set")
+
+// checks that the email is not registered yet, run by pkg.BindAndValidateUsing
+func (u *User_Signup_Request) Validate(ctx context.Context, users UserLookup) (map[string]string, error) {
+	if users == nil {
+		return nil, errNoUserLookup
+	}
+	existingUser, err := users.FindByEmail(ctx, u.Email)
+	if err != nil {
+		return nil, err
+	}
+	if existingUser.ID != 0 {
+		return map[string]string{"email": pkg.ValidationMessage(ctx, "email_taken")}, nil
+	}
+	return nil, nil
+}
@@ -1,14 +1,17 @@
 package handlers
 
 import (
+	"errors"
 	"go-rest-template/internal/db"
 	"go-rest-template/internal/dto"
 	"go-rest-template/internal/middlewares"
 	"go-rest-template/internal/repository"
 	"go-rest-template/pkg"
 	"go-rest-template/pkg/api"
-	"go-rest-template/pkg/logger"
+	"go-rest-template/pkg/metrics"
 	"net/http"
+
+	"github.com/jackc/pgx/v5/pgconn"
 )
 
 type UserHandler struct {
@@ -22,105 +25,89 @@ func NewUserHandler(repo *repository.UserRepository) *UserHandler {
 }
 
 // POST /auth/signup
-func (h *UserHandler) Signup(w http.ResponseWriter, r *http.Request) {
-	body, validationErrors, err := pkg.BindAndValidate[dto.User_Signup_Request](r)
+func (h *UserHandler) Signup(w http.ResponseWriter, r *http.Request) error {
+	body, validationErrors, err := pkg.BindAndValidateUsing[dto.User_Signup_Request, dto.UserLookup](r, pkg.DefaultBindOptions, h.repo)
 	if err != nil {
-		api.Error(w, err.Error())
-		return
+		return err
 	}
 	if validationErrors != nil {
-		api.ValidationErrors(w, validationErrors)
-		return
+		return api.ErrValidation(validationErrors)
 	}
 
-	existingUser, err := h.repo.FindByEmail(r.Context(), body.Email)
-	if err != nil {
-		logger.Debug(err)
-		api.InternalServerError(w)
-		return
-	}
-	if existingUser.ID != 0 {
-		api.Error(w, "User already exists")
-		return
-	}
-	hash, err := pkg.GenerateHash(body.Password)
+	hash, err := pkg.GenerateHash(r.Context(), body.Password)
 	if err != nil {
-		logger.Error(err)
-		api.InternalServerError(w)
-		return
+		return api.ErrInternal(err)
 	}
 	id, err := h.repo.CreateNew(r.Context(), db.CreateUserParams{
 		Email:        body.Email,
 		PasswordHash: hash,
 	})
 	if err != nil {
-		logger.Error(err)
-		api.InternalServerError(w)
-		return
+		// unique_violation: lost the race against a concurrent signup with the same email
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == "23505" {
+			return api.ErrConflict("User already exists")
+		}
+		return api.ErrInternal(err)
 	}
 	newUser, err := h.repo.FindById(r.Context(), id)
 	if err != nil {
-		logger.Error(err)
-		api.InternalServerError(w)
-		return
+		return api.ErrInternal(err)
 	}
 	if newUser.ID == 0 {
-		api.Error(w, "User not found")
-		return
+		return api.ErrNotFound("User not found")
 	}
 
 	token, err := pkg.GenerateToken(newUser.Email)
 	if err != nil {
-		logger.Error(err)
-		api.InternalServerError(w)
-		return
+		return api.ErrInternal(err)
 	}
-	api.SendJWTtoken(w, r, token, "Signed up successfully", newUser)
+	api.SetJWTCookie(w, token)
+	api.Created(w, "/api/user/profile", "Signed up successfully", newUser)
+	return nil
 }
 
 // POST /auth/login
-func (h *UserHandler) Login(w http.ResponseWriter, r *http.Request) {
+func (h *UserHandler) Login(w http.ResponseWriter, r *http.Request) error {
 	body, validationErrors, err := pkg.BindAndValidate[dto.User_Login_Request](r)
 	if err != nil {
-		api.Error(w, err.Error())
-		return
+		return err
 	}
 	if validationErrors != nil {
-		api.ValidationErrors(w, validationErrors)
-		return
+		return api.ErrValidation(validationErrors)
 	}
 
 	user, err := h.repo.FindByEmail(r.Context(), body.Email)
 	if err != nil {
-		logger.Error(err)
-		api.InternalServerError(w)
-		return
+		return api.ErrInternal(err)
 	}
 	if user.ID == 0 {
-		api.Error(w, "Invalid credentials")
-		return
+		metrics.Login(false)
+		return api.ErrUnauthorized("Invalid credentials")
 	}
-	if notMatched := pkg.CompareHashAndPassword(user.PasswordHash, body.Password); notMatched != nil {
-		api.Error(w, "Invalid credentials")
-		return
+	if notMatched := pkg.CompareHashAndPassword(r.Context(), user.PasswordHash, body.Password); notMatched != nil {
+		metrics.Login(false)
+		return api.ErrUnauthorized("Invalid credentials")
 	}
+	metrics.Login(true)
 
 	token, err := pkg.GenerateToken(user.Email)
 	if err != nil {
-		logger.Error(err)
-		api.InternalServerError(w)
-		return
+		return api.ErrInternal(err)
 	}
 	api.SendJWTtoken(w, r, token, "Logged in successfully", user)
+	return nil
 }
 
 // GET /auth/logout
-func (h *UserHandler) Logout(w http.ResponseWriter, r *http.Request) {
+func (h *UserHandler) Logout(w http.ResponseWriter, r *http.Request) error {
 	api.Logout(w)
+	return nil
 }
 
 // GET /user/profile
-func (h *UserHandler) GetMyDetails(w http.ResponseWriter, r *http.Request) {
+func (h *UserHandler) GetMyDetails(w http.ResponseWriter, r *http.Request) error {
 	user := middlewares.GetUserFromContext(r.Context())
 	api.Success(w, "Success", user)
+	return nil
 }
@@ -4,9 +4,9 @@ import (
 	"context"
 
 	"go-rest-template/internal/db"
-	"go-rest-template/internal/models"
 	"go-rest-template/pkg"
 	"go-rest-template/pkg/api"
+	"go-rest-template/pkg/logger"
 	"net/http"
 )
 
@@ -36,6 +36,7 @@ func IsAuthenticated(q *db.Queries) func(http.Handler) http.Handler {
 				api.Unauthorized(w, "Unauthorized")
 				return
 			}
+			logger.AddFields(r.Context(), logger.Fields{"user_id": user.ID})
 			ctx := context.WithValue(r.Context(), userContextKey, &user)
 			next.ServeHTTP(w, r.WithContext(ctx))
 		})
@@ -43,8 +44,8 @@ func IsAuthenticated(q *db.Queries) func(http.Handler) http.Handler {
 }
 
 // returns the user from the context
-func GetUserFromContext(ctx context.Context) *models.User {
-	user, ok := ctx.Value(userContextKey).(*models.User)
+func GetUserFromContext(ctx context.Context) *db.User {
+	user, ok := ctx.Value(userContextKey).(*db.User)
 	if !ok {
 		return nil
 	}
@@ -2,9 +2,10 @@ package repository
 
 import (
 	"context"
-	"database/sql"
 	"errors"
 	"go-rest-template/internal/db"
+
+	"github.com/jackc/pgx/v5"
 )
 
 type UserRepository struct {
@@ -24,7 +25,7 @@ func (r *UserRepository) CreateNew(ctx context.Context, user db.CreateUserParams
 func (r *UserRepository) FindById(ctx context.Context, id int32) (db.User, error) {
 	user, err := r.Queries.GetUserByID(ctx, id)
 	if err != nil {
-		if errors.Is(err, sql.ErrNoRows) {
+		if errors.Is(err, pgx.ErrNoRows) {
 			return db.User{}, nil
 		}
 		return db.User{}, err
@@ -35,7 +36,7 @@ func (r *UserRepository) FindById(ctx context.Context, id int32) (db.User, error
 func (r *UserRepository) FindByEmail(ctx context.Context, email string) (db.User, error) {
 	user, err := r.Queries.GetUserByEmail(ctx, email)
 	if err != nil {
-		if errors.Is(err, sql.ErrNoRows) {
+		if errors.Is(err, pgx.ErrNoRows) {
 			return db.User{}, nil
 		}
 		return db.User{}, err
@@ -0,0 +1,42 @@
+package routes
+
+import (
+	"bytes"
+	"os"
+	"strings"
+	"testing"
+)
+
+// the committed document must match the one generated from the routes, run
+// `make openapi` after changing a route or DTO
+func TestOpenAPIDocumentUpToDate(t *testing.T) {
+	doc, err := OpenAPIDocument()
+	if err != nil {
+		t.Fatalf("generating OpenAPI document: %v", err)
+	}
+	generated, err := doc.Marshal()
+	if err != nil {
+		t.Fatalf("encoding OpenAPI document: %v", err)
+	}
+	committed, err := os.ReadFile("../../docs/openapi.json")
+	if err != nil {
+		t.Fatalf("reading committed document: %v", err)
+	}
+	if bytes.Equal(committed, generated) {
+		return
+	}
+
+	want, got := strings.Split(string(committed), "\n"), strings.Split(string(generated), "\n")
+	for i := 0; i < max(len(want), len(got)); i++ {
+		var w, g string
+		if i < len(want) {
+			w = want[i]
+		}
+		if i < len(got) {
+			g = got[i]
+		}
+		if w != g {
+			t.Fatalf("docs/openapi.json is out of date, run `make openapi`\nline %d:\n  committed: %s\n  generated: %s", i+1, w, g)
+		}
+	}
+}
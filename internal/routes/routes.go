@@ -0,0 +1,26 @@
+package routes
+
+import (
+	"go-rest-template/internal/db"
+	"go-rest-template/pkg/openapi"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// registers every API route group under /api
+func RegisterAPIRoutes(r chi.Router, q *db.Queries) {
+	r.Route("/api", func(api chi.Router) {
+		RegisterUserRoutes(api, q)
+	})
+}
+
+// builds the OpenAPI document of the API routes, no database is needed.
+// It doesn't depend on the environment so it can be committed and checked.
+func OpenAPIDocument() (*openapi.Document, error) {
+	r := chi.NewRouter()
+	RegisterAPIRoutes(r, db.New(nil))
+	return openapi.Generate(r, openapi.Info{
+		Title:   "Go REST API",
+		Version: "1.0.0",
+	})
+}
@@ -2,9 +2,13 @@ package routes
 
 import (
 	"go-rest-template/internal/db"
+	"go-rest-template/internal/dto"
 	"go-rest-template/internal/handlers"
 	"go-rest-template/internal/middlewares"
 	"go-rest-template/internal/repository"
+	"go-rest-template/pkg/api"
+	"go-rest-template/pkg/openapi"
+	"net/http"
 
 	"github.com/go-chi/chi/v5"
 )
@@ -14,12 +18,25 @@ func RegisterUserRoutes(r chi.Router, q *db.Queries) {
 	h := handlers.NewUserHandler(repo)
 
 	r.Route("/auth", func(r chi.Router) {
-		r.Post("/signup", h.Signup)
-		r.Post("/login", h.Login)
-		r.Get("/logout", h.Logout)
+		r.Method(http.MethodPost, "/signup", openapi.Describe(openapi.Route{
+			ID: "signup", Summary: "Create an account and log in", Tags: []string{"auth"},
+			Request: dto.User_Signup_Request{}, Response: db.User{}, Status: http.StatusCreated,
+			Errors: map[int]string{http.StatusConflict: "A user with the email already exists"},
+		}, api.HandlerFunc(h.Signup)))
+		r.Method(http.MethodPost, "/login", openapi.Describe(openapi.Route{
+			ID: "login", Summary: "Log in with email and password", Tags: []string{"auth"},
+			Request: dto.User_Login_Request{}, Response: db.User{},
+			Errors: map[int]string{http.StatusUnauthorized: "Invalid credentials"},
+		}, api.HandlerFunc(h.Login)))
+		r.Method(http.MethodGet, "/logout", openapi.Describe(openapi.Route{
+			ID: "logout", Summary: "Clear the access token cookie", Tags: []string{"auth"},
+		}, api.HandlerFunc(h.Logout)))
 	})
 
 	r.Route("/user", func(r chi.Router) {
-		r.With(middlewares.IsAuthenticated(q)).Get("/profile", h.GetMyDetails)
+		r.With(middlewares.IsAuthenticated(q)).Method(http.MethodGet, "/profile", openapi.Describe(openapi.Route{
+			ID: "getProfile", Summary: "Get the logged in user", Tags: []string{"user"},
+			Response: db.User{}, Auth: true,
+		}, api.HandlerFunc(h.GetMyDetails)))
 	})
 }
@@ -2,118 +2,311 @@ package main
 
 import (
 	"context"
+	"errors"
 	"flag"
 	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
 
 	"go-rest-template/internal/db"
 	dbConn "go-rest-template/internal/db/conn"
+	_ "go-rest-template/internal/db/migrations"
 	"go-rest-template/internal/routes"
-	"os"
 
+	"go-rest-template/pkg/api"
 	"go-rest-template/pkg/config"
+	"go-rest-template/pkg/health"
 	"go-rest-template/pkg/logger"
+	"go-rest-template/pkg/metrics"
+	"go-rest-template/pkg/openapi"
+	"go-rest-template/pkg/security"
+	"go-rest-template/pkg/tlsconfig"
+	"go-rest-template/pkg/tracing"
 	"net/http"
 
 	"github.com/go-chi/chi/v5"
 	chiMiddleware "github.com/go-chi/chi/v5/middleware"
-	"github.com/go-chi/cors"
-	_ "github.com/joho/godotenv/autoload"
 )
 
+const migrationsDir = "./schema/migrations"
+
 func main() {
+	fs := flag.NewFlagSet(os.Args[0], flag.ExitOnError)
+	printConfig := fs.Bool("print-config", false, "print the configuration with secrets redacted and exit")
+	cfg, err := config.Load(fs, os.Args[1:])
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "invalid configuration:\n%v\n", err)
+		os.Exit(2)
+	}
+	if *printConfig {
+		if err := cfg.Print(os.Stdout); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		return
+	}
+	if err := cfg.Validate(); err != nil {
+		fmt.Fprintf(os.Stderr, "invalid configuration:\n%v\n", err)
+		os.Exit(2)
+	}
+
+	if err := run(); err != nil {
+		logger.Error("Server failed:", err)
+		logger.Close()
+		os.Exit(1)
+	}
+	logger.Close()
+}
+
+// runs the server until SIGINT or SIGTERM, cleaning up in reverse order of setup
+func run() error {
 	logger.InfoF("Running in `%s` mode", config.APP().GO_ENV)
-	// Command line flags
-	var (
-		runMigrations = flag.Bool("migrate", false, "Run database migrations")
-		rollback      = flag.Int("rollback", 0, "Rollback n migrations")
-		showVersion   = flag.Bool("version", false, "Show current migration version")
-	)
-	flag.Parse()
+	// tracing, before the database so queries are traced
+	shutdownTracing, err := tracing.Setup(context.Background())
+	if err != nil {
+		return fmt.Errorf("setting up tracing: %w", err)
+	}
+	defer func() {
+		if err := shutdownTracing(context.Background()); err != nil {
+			logger.ErrorF("Failed to flush traces: %v", err)
+		}
+	}()
 
 	// Database connection
 	pool := dbConn.ConnectToDB()
-	defer pool.Close()
+	defer func() {
+		pool.Close()
+		logger.Info("Database pool closed")
+	}()
 	if err := pool.Ping(context.Background()); err != nil {
-		logger.Panic("Failed to ping database:", err)
+		return fmt.Errorf("pinging database: %w", err)
 	}
 
-	// migrations
-	migrationPath := "./schema/migrations"
-
-	switch {
-	case *runMigrations || config.APP().MIGRATE_ON_START:
+	// migrations, see cmd/migrate for manual control
+	if config.APP().MIGRATE_ON_START {
 		logger.Info("Running migrations...")
-		if err := dbConn.RunMigrations(pool, migrationPath); err != nil {
-			logger.PanicF("Migration failed: %v", err)
+		if err := dbConn.RunMigrations(pool, migrationsDir); err != nil {
+			return fmt.Errorf("running migrations: %w", err)
 		}
 		logger.Info("Migration completed.")
-		if !config.APP().MIGRATE_ON_START {
-			os.Exit(0)
-		}
-	case *rollback > 0:
-		logger.InfoF("Rolling back %d migrations...\n", *rollback)
-		if err := dbConn.RollbackMigrations(pool, migrationPath, *rollback); err != nil {
-			logger.PanicF("Rollback failed: %v", err)
-		}
-		os.Exit(0)
-	case *showVersion:
-		v, dirty, err := dbConn.GetMigrationVersion(pool)
-		if err != nil {
-			logger.PanicF("Error reading migration version: %v", err)
-		}
-		logger.InfoF("Current migration version: %s (dirty: %v)", v, dirty)
-		os.Exit(0)
-	default:
-		logger.Info("No migration action specified.")
 	}
 
-	r := chi.NewRouter()
-	r.Use(chiMiddleware.Logger)
-	r.Use(chiMiddleware.Recoverer)
-	r.Use(chiMiddleware.RealIP)
-	r.Use(chiMiddleware.RequestID)
-	if config.APP().GO_ENV == "production" {
-		r.Use(cors.Handler(cors.Options{
-			AllowedOrigins: []string{
-				config.APP().CLIENT_URL,
-			},
-			AllowedMethods:   []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"},
-			AllowedHeaders:   []string{"Accept", "Authorization", "Content-Type", "X-CSRF-Token"},
-			AllowCredentials: true,
-			MaxAge:           300,
-		}))
+	// database metrics
+	metrics.RegisterPool(pool)
+	metrics.RegisterMigrationVersion(func(ctx context.Context) (string, error) {
+		version, _, err := dbConn.GetMigrationVersion(ctx, pool)
+		return version, err
+	})
+
+	// readiness checks served at /readyz
+	health.Register("database", config.APP().HEALTH_CHECK_TIMEOUT, pool.Ping)
+	// the migration files are read once, images without them skip the check
+	if versions, err := dbConn.LocalVersions(migrationsDir); err != nil {
+		logger.InfoF("Migrations readiness check disabled, cannot read %s: %v", migrationsDir, err)
 	} else {
-		r.Use(devCors)
+		health.Register("migrations", config.APP().HEALTH_CHECK_TIMEOUT, func(ctx context.Context) error {
+			pending, err := dbConn.PendingMigrations(ctx, pool, versions)
+			if err != nil {
+				return err
+			}
+			if len(pending) > 0 {
+				return fmt.Errorf("%d pending migrations, latest %s", len(pending), pending[len(pending)-1])
+			}
+			return nil
+		})
 	}
-	r.Use(chiMiddleware.Heartbeat("/api/health"))
+
+	// API documentation
+	spec, err := routes.OpenAPIDocument()
+	if err != nil {
+		return fmt.Errorf("generating OpenAPI document: %w", err)
+	}
+	if config.APP().API_URL != "" {
+		spec.Servers = []openapi.Server{{URL: config.APP().API_URL}}
+	}
+
+	r := chi.NewRouter()
+	r.Use(chiMiddleware.RequestID)
+	// proxy headers are only honoured from TRUSTED_PROXIES
+	r.Use(security.RealIP(config.APP().TRUSTED_PROXIES))
+	r.Use(tracing.Middleware)
+	r.Use(logger.RequestLogger)
+	r.Use(metrics.Middleware)
+	r.Use(chiMiddleware.Recoverer)
+	r.Use(security.Headers)
+	// the OpenAPI document is public so external tools can load it
+	security.RegisterCORS("/api/openapi.json", security.CORSPolicy{
+		AllowedOrigins: []string{"*"},
+		AllowedMethods: []string{http.MethodGet},
+		MaxAge:         config.APP().CORS_MAX_AGE,
+	})
+	// before Negotiate so preflights are answered and 406 and problem responses carry CORS headers
+	r.Use(security.CORS())
+	r.Use(api.Negotiate)
+	r.Use(api.ProblemDetails(api.ProblemOnAccept))
+	r.Use(health.Heartbeat("/api/health"))
+	// reject requests that don't match the spec, responses are checked outside production
+	r.Use(openapi.ValidateRequests(spec, openapi.ValidatorOptions{
+		ValidateResponses: config.APP().GO_ENV != "production",
+	}))
 
 	// Injectors
 	q := db.New(pool)
 
 	// register routes
-	r.Route("/api", func(api chi.Router) {
-		routes.RegisterUserRoutes(api, q)
-	})
+	routes.RegisterAPIRoutes(r, q)
+
+	// probes, /api/health is kept for existing load balancer configurations
+	r.Get("/healthz", health.Liveness)
+	r.Get("/readyz", health.Readiness)
+
+	// Content-Security-Policy violation reports sent by browsers, limited per client
+	// as the route is public
+	r.With(security.RateLimit(60, time.Minute)).Post(security.ReportPath, security.ReportHandler)
+
+	// API documentation
+	specHandler, err := openapi.Handler(spec)
+	if err != nil {
+		return fmt.Errorf("encoding OpenAPI document: %w", err)
+	}
+	r.Method(http.MethodGet, "/api/openapi.json", specHandler)
+	if config.APP().GO_ENV != "production" {
+		// the docs page loads Redoc from its CDN and uses inline styles
+		r.With(security.Override(func(p *security.Policy) {
+			p.ContentSecurityPolicy = "default-src 'none'; script-src https://cdn.redoc.ly blob:; worker-src blob:; " +
+				"style-src 'unsafe-inline' https://fonts.googleapis.com; font-src https://fonts.gstatic.com; " +
+				"img-src 'self' data: https://cdn.redoc.ly; connect-src 'self'; frame-ancestors 'none'"
+			p.CrossOriginEmbedderPolicy = ""
+		})).Method(http.MethodGet, "/api/docs", openapi.DocsHandler("/api/openapi.json"))
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	// reload the configuration on SIGHUP or when the config file changes
+	go func() {
+		if err := config.Watch(ctx, logReload); err != nil {
+			logger.ErrorF("configuration watcher failed, changes need a restart: %v", err)
+		}
+	}()
+
+	apiServer := newServer(fmt.Sprintf(":%d", config.APP().PORT), r)
+	servers := []*http.Server{apiServer}
+	if tlsconfig.Enabled() {
+		tlsCfg, certs, err := tlsconfig.New()
+		if err != nil {
+			return err
+		}
+		apiServer.TLSConfig = tlsCfg
+		// pick up renewed certificates without a restart
+		go func() {
+			err := certs.Watch(ctx, func(err error) {
+				if err != nil {
+					logger.ErrorF("TLS certificate reload failed, keeping the current one: %v", err)
+					return
+				}
+				logger.Info("TLS certificate reloaded")
+			})
+			if err != nil {
+				logger.ErrorF("TLS certificate watcher failed, renewals need a restart: %v", err)
+			}
+		}()
+		if addr := config.APP().TLS_REDIRECT_ADDR; addr != "" {
+			servers = append(servers, newServer(addr, tlsconfig.RedirectHandler(config.APP().PORT)))
+		}
+	}
+	// metrics are served on a separate admin port, or on the API router when METRICS_ADDR is empty
+	if addr := config.APP().METRICS_ADDR; addr != "" {
+		admin := http.NewServeMux()
+		admin.Handle("/metrics", metrics.Handler())
+		servers = append(servers, newServer(addr, admin))
+	} else {
+		r.Method(http.MethodGet, "/metrics", metrics.Handler())
+	}
 
-	logger.InfoF("Server listening on :%d", config.APP().PORT)
-	logger.Panic(http.ListenAndServe(fmt.Sprintf(":%d", (config.APP().PORT)), r))
+	serveErr := make(chan error, len(servers))
+	for _, srv := range servers {
+		go func() {
+			var err error
+			if srv.TLSConfig != nil {
+				logger.InfoF("Server listening on %s with TLS", srv.Addr)
+				err = srv.ListenAndServeTLS("", "")
+			} else {
+				logger.InfoF("Server listening on %s", srv.Addr)
+				err = srv.ListenAndServe()
+			}
+			if !errors.Is(err, http.ErrServerClosed) {
+				serveErr <- fmt.Errorf("server on %s: %w", srv.Addr, err)
+			}
+		}()
+	}
+	health.SetReady(true)
+
+	select {
+	case err := <-serveErr:
+		health.SetReady(false)
+		shutdown(servers)
+		return err
+	case <-ctx.Done():
+	}
+	// a second signal stops the process without waiting
+	stop()
+
+	// report not ready first so load balancers stop sending requests, then drain
+	health.SetReady(false)
+	logger.InfoF("Shutting down, draining for %s", config.APP().SHUTDOWN_DRAIN)
+	time.Sleep(config.APP().SHUTDOWN_DRAIN)
+	return shutdown(servers)
 }
 
-// CORS middleware for development environment
-func devCors(next http.Handler) http.Handler {
-	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		origin := r.Header.Get("Origin")
-		if origin != "" {
-			w.Header().Set("Access-Control-Allow-Origin", origin)
-			w.Header().Set("Vary", "Origin")
-			w.Header().Set("Access-Control-Allow-Credentials", "true")
-			w.Header().Set("Access-Control-Allow-Headers", "Authorization, Content-Type, X-CSRF-Token")
-			w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE, OPTIONS")
+// returns a server with the timeouts and header limit from the configuration
+func newServer(addr string, handler http.Handler) *http.Server {
+	cfg := config.APP()
+	return &http.Server{
+		Addr:              addr,
+		Handler:           handler,
+		ReadTimeout:       cfg.HTTP_READ_TIMEOUT,
+		ReadHeaderTimeout: cfg.HTTP_READ_HEADER_TIMEOUT,
+		WriteTimeout:      cfg.HTTP_WRITE_TIMEOUT,
+		IdleTimeout:       cfg.HTTP_IDLE_TIMEOUT,
+		MaxHeaderBytes:    cfg.HTTP_MAX_HEADER_BYTES,
+	}
+}
+
+// stops accepting connections and waits up to SHUTDOWN_TIMEOUT for in-flight requests
+func shutdown(servers []*http.Server) error {
+	ctx, cancel := context.WithTimeout(context.Background(), config.APP().SHUTDOWN_TIMEOUT)
+	defer cancel()
+	var errs []error
+	for _, srv := range servers {
+		if err := srv.Shutdown(ctx); err != nil {
+			errs = append(errs, fmt.Errorf("shutting down server on %s: %w", srv.Addr, err))
 		}
-		if r.Method == http.MethodOptions {
-			w.WriteHeader(http.StatusNoContent)
-			return
+	}
+	if err := errors.Join(errs...); err != nil {
+		return err
+	}
+	logger.Info("Servers stopped")
+	return nil
+}
+
+// logs the outcome of a configuration reload
+func logReload(changes []config.Change, err error) {
+	if err != nil {
+		logger.ErrorF("Configuration reload rejected: %v", err)
+		return
+	}
+	if len(changes) == 0 {
+		logger.Info("Configuration reloaded, nothing changed")
+		return
+	}
+	for _, c := range changes {
+		if c.Restart {
+			logger.InfoF("Configuration %s changed from %v to %v, restart to apply", c.Key, c.Old, c.New)
+			continue
 		}
-		next.ServeHTTP(w, r)
-	})
+		logger.InfoF("Configuration %s changed from %v to %v", c.Key, c.Old, c.New)
+	}
 }
@@ -1,7 +1,6 @@
 package api
 
 import (
-	"encoding/json"
 	"go-rest-template/pkg/config"
 	"net/http"
 	"time"
@@ -11,6 +10,7 @@ type Response struct {
 	Success bool   `json:"success"`
 	Message string `json:"message,omitempty"`
 	Data    any    `json:"data,omitempty"`
+	Meta    any    `json:"meta,omitempty"`
 	Errors  any    `json:"errors,omitempty"`
 }
 
@@ -25,26 +25,19 @@ func Success(w http.ResponseWriter, message string, data any) {
 
 // sends an error JSON response with status 400
 func Error(w http.ResponseWriter, message string) {
-	writeJSON(w, http.StatusBadRequest, Response{
-		Success: false,
-		Message: message,
-	})
+	writeError(w, NewProblem(http.StatusBadRequest, message))
 }
 
 // sends a 422 JSON response with validation error map
 func ValidationErrors(w http.ResponseWriter, errors map[string]string) {
-	writeJSON(w, http.StatusUnprocessableEntity, Response{
-		Success: false,
-		Errors:  errors,
-	})
+	p := NewProblem(http.StatusUnprocessableEntity, "")
+	p.Errors = errors
+	writeError(w, p)
 }
 
 // sends a JSON response with a custom status code
 func AbortWithStatusError(w http.ResponseWriter, code int, message string) {
-	writeJSON(w, code, Response{
-		Success: false,
-		Message: message,
-	})
+	writeError(w, NewProblem(code, message))
 }
 
 // sends a 404 response
@@ -69,6 +62,12 @@ func Forbidden(w http.ResponseWriter, message string) {
 
 // sets a secure cookie and sends a success response
 func SendJWTtoken(w http.ResponseWriter, r *http.Request, token string, message string, data any) {
+	SetJWTCookie(w, token)
+	Success(w, message, data)
+}
+
+// sets the secure access_token cookie without writing a response
+func SetJWTCookie(w http.ResponseWriter, token string) {
 	cookie := &http.Cookie{
 		Name:     "access_token",
 		Value:    token,
@@ -86,7 +85,6 @@ func SendJWTtoken(w http.ResponseWriter, r *http.Request, token string, message
 	}
 
 	http.SetCookie(w, cookie)
-	Success(w, message, data)
 }
 
 // removes the JWT cookie
@@ -102,10 +100,3 @@ func Logout(w http.ResponseWriter) {
 	http.SetCookie(w, cookie)
 	Success(w, "Logged out successfully", nil)
 }
-
-// a helper function to write a JSON response
-func writeJSON(w http.ResponseWriter, statusCode int, resp Response) {
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(statusCode)
-	json.NewEncoder(w).Encode(resp)
-}
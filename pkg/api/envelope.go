@@ -0,0 +1,79 @@
+package api
+
+import (
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// PageMeta is the `meta` member of a list response.
+type PageMeta struct {
+	// total number of items, nil when the total is unknown (e.g. cursor pagination)
+	Total  *int64 `json:"total,omitempty"`
+	Limit  int    `json:"limit"`
+	Cursor string `json:"cursor,omitempty"`
+	Next   string `json:"next,omitempty"`
+	Prev   string `json:"prev,omitempty"`
+}
+
+// sends a 200 JSON response with typed data
+func OK[T any](w http.ResponseWriter, message string, data T) {
+	Success(w, message, data)
+}
+
+// sends a 201 JSON response with the created resource and its Location header
+func Created[T any](w http.ResponseWriter, location string, message string, data T) {
+	if location != "" {
+		w.Header().Set("Location", location)
+	}
+	writeJSON(w, http.StatusCreated, Response{
+		Success: true,
+		Message: message,
+		Data:    data,
+	})
+}
+
+// sends a 204 response without a body
+func NoContent(w http.ResponseWriter) {
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// sends a 200 JSON list response with pagination meta and a Link header
+// pointing to the next and previous pages
+func Page[T any](w http.ResponseWriter, message string, items []T, meta PageMeta) {
+	if items == nil {
+		items = []T{}
+	}
+	var links []string
+	if meta.Next != "" {
+		links = append(links, `<`+meta.Next+`>; rel="next"`)
+	}
+	if meta.Prev != "" {
+		links = append(links, `<`+meta.Prev+`>; rel="prev"`)
+	}
+	if len(links) > 0 {
+		w.Header().Set("Link", strings.Join(links, ", "))
+	}
+	writeJSON(w, http.StatusOK, Response{
+		Success: true,
+		Message: message,
+		Data:    items,
+		Meta:    meta,
+	})
+}
+
+// returns the request URL with the given query parameters replaced,
+// for building next/prev page links. An empty value removes the parameter.
+func PageLink(r *http.Request, params map[string]string) string {
+	u := url.URL{Path: r.URL.Path}
+	query := r.URL.Query()
+	for k, v := range params {
+		if v == "" {
+			query.Del(k)
+			continue
+		}
+		query.Set(k, v)
+	}
+	u.RawQuery = query.Encode()
+	return u.String()
+}
@@ -0,0 +1,103 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"go-rest-template/pkg/logger"
+	"net/http"
+)
+
+// HandlerFunc is an http handler that returns its error instead of writing it.
+// Errors are mapped to a response centrally, so handlers only hold business logic:
+//
+//	r.Method(http.MethodPost, "/signup", api.HandlerFunc(h.Signup))
+type HandlerFunc func(w http.ResponseWriter, r *http.Request) error
+
+func (fn HandlerFunc) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if err := fn(w, r); err != nil {
+		RenderError(w, r, err)
+	}
+}
+
+// HTTPError is a typed handler error carrying the status it maps to.
+// Internal errors keep their cause for logging, it is never sent to the client.
+type HTTPError struct {
+	Status  int
+	Message string
+	Fields  map[string]string
+	Cause   error
+}
+
+func (e *HTTPError) Error() string {
+	if e.Cause != nil {
+		return fmt.Sprintf("%s: %v", e.Message, e.Cause)
+	}
+	return e.Message
+}
+
+func (e *HTTPError) Unwrap() error {
+	return e.Cause
+}
+
+func (e *HTTPError) problem() *Problem {
+	p := NewProblem(e.Status, e.Message)
+	p.Errors = e.Fields
+	return p
+}
+
+// returns an error that is rendered with the given status code and message
+func ErrStatus(status int, message string) *HTTPError {
+	return &HTTPError{Status: status, Message: message}
+}
+
+// 400 error
+func ErrBadRequest(message string) *HTTPError {
+	return ErrStatus(http.StatusBadRequest, message)
+}
+
+// 401 error
+func ErrUnauthorized(message string) *HTTPError {
+	return ErrStatus(http.StatusUnauthorized, message)
+}
+
+// 403 error
+func ErrForbidden(message string) *HTTPError {
+	return ErrStatus(http.StatusForbidden, message)
+}
+
+// 404 error
+func ErrNotFound(message string) *HTTPError {
+	return ErrStatus(http.StatusNotFound, message)
+}
+
+// 409 error
+func ErrConflict(message string) *HTTPError {
+	return ErrStatus(http.StatusConflict, message)
+}
+
+// 422 error with a map of field errors keyed by JSON path
+func ErrValidation(fields map[string]string) *HTTPError {
+	return &HTTPError{Status: http.StatusUnprocessableEntity, Fields: fields}
+}
+
+// 500 error wrapping the cause, which is logged but not sent to the client
+func ErrInternal(cause error) *HTTPError {
+	return &HTTPError{Status: http.StatusInternalServerError, Message: "Internal server error", Cause: cause}
+}
+
+// maps a handler error to the problem to render, logging internal causes
+func problemFor(r *http.Request, err error) *Problem {
+	var p *Problem
+	if errors.As(err, &p) {
+		return p
+	}
+
+	var httpErr *HTTPError
+	if !errors.As(err, &httpErr) {
+		httpErr = ErrInternal(err)
+	}
+	if httpErr.Status >= http.StatusInternalServerError {
+		logger.FromContext(r.Context()).Error("request failed", logger.Fields{"status": httpErr.Status, "error": err})
+	}
+	return httpErr.problem()
+}
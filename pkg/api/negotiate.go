@@ -0,0 +1,278 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"go-rest-template/pkg/config"
+	"go-rest-template/pkg/logger"
+	"io"
+	"mime"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/fxamacker/cbor/v2"
+	"github.com/vmihailenco/msgpack/v5"
+)
+
+// Encoder serializes response bodies for one media type.
+type Encoder interface {
+	// media type sent as Content-Type and matched against the Accept header
+	ContentType() string
+	Encode(w io.Writer, v any, pretty bool) error
+}
+
+type jsonEncoder struct{}
+
+func (jsonEncoder) ContentType() string { return "application/json" }
+
+func (jsonEncoder) Encode(w io.Writer, v any, pretty bool) error {
+	enc := json.NewEncoder(w)
+	if pretty {
+		enc.SetIndent("", "  ")
+	}
+	return enc.Encode(v)
+}
+
+// MessagePack encoder, uses the json struct tags
+type msgpackEncoder struct{}
+
+func (msgpackEncoder) ContentType() string { return "application/msgpack" }
+
+func (msgpackEncoder) Encode(w io.Writer, v any, _ bool) error {
+	enc := msgpack.NewEncoder(w)
+	enc.SetCustomStructTag("json")
+	return enc.Encode(v)
+}
+
+// CBOR encoder, falls back to the json struct tags
+type cborEncoder struct{}
+
+func (cborEncoder) ContentType() string { return "application/cbor" }
+
+func (cborEncoder) Encode(w io.Writer, v any, _ bool) error {
+	return cbor.NewEncoder(w).Encode(v)
+}
+
+var (
+	JSONEncoder    Encoder = jsonEncoder{}
+	MsgpackEncoder Encoder = msgpackEncoder{}
+	CBOREncoder    Encoder = cborEncoder{}
+)
+
+var (
+	encodersMu sync.RWMutex
+	// in order of preference when the client accepts several
+	encoders = []Encoder{JSONEncoder, MsgpackEncoder, CBOREncoder}
+)
+
+// RegisterEncoder adds an encoder (e.g. protobuf) or replaces the one
+// registered for the same content type.
+func RegisterEncoder(e Encoder) {
+	encodersMu.Lock()
+	defer encodersMu.Unlock()
+	for i, existing := range encoders {
+		if existing.ContentType() == e.ContentType() {
+			encoders[i] = e
+			return
+		}
+	}
+	encoders = append(encoders, e)
+}
+
+type mediaRange struct {
+	mediaType string
+	q         float64
+}
+
+// parses an Accept header into media ranges ordered by preference
+func parseAccept(accept string) []mediaRange {
+	var ranges []mediaRange
+	for _, part := range strings.Split(accept, ",") {
+		mediaType, params, err := mime.ParseMediaType(strings.TrimSpace(part))
+		if err != nil {
+			continue
+		}
+		q := 1.0
+		if v, ok := params["q"]; ok {
+			if parsed, err := strconv.ParseFloat(v, 64); err == nil {
+				q = parsed
+			}
+		}
+		if q > 0 {
+			ranges = append(ranges, mediaRange{mediaType: mediaType, q: q})
+		}
+	}
+	sort.SliceStable(ranges, func(i, j int) bool {
+		if ranges[i].q != ranges[j].q {
+			return ranges[i].q > ranges[j].q
+		}
+		// more specific ranges first
+		return strings.Count(ranges[i].mediaType, "*") < strings.Count(ranges[j].mediaType, "*")
+	})
+	return ranges
+}
+
+func mediaTypeMatches(pattern, mediaType string) bool {
+	if pattern == "*/*" || pattern == mediaType {
+		return true
+	}
+	prefix, ok := strings.CutSuffix(pattern, "/*")
+	return ok && strings.HasPrefix(mediaType, prefix+"/")
+}
+
+// returns the encoder for the Accept header, JSON when the header is empty
+// and nil when none of the accepted media types can be produced
+func negotiateEncoder(accept string) Encoder {
+	if strings.TrimSpace(accept) == "" {
+		return JSONEncoder
+	}
+	encodersMu.RLock()
+	defer encodersMu.RUnlock()
+	for _, mr := range parseAccept(accept) {
+		if mr.mediaType == ProblemContentType {
+			return JSONEncoder
+		}
+		for _, e := range encoders {
+			if mediaTypeMatches(mr.mediaType, e.ContentType()) {
+				return e
+			}
+		}
+	}
+	return nil
+}
+
+// reports whether the response should be indented, through ?pretty or in development
+func wantsPretty(r *http.Request) bool {
+	query := r.URL.Query()
+	if query.Has("pretty") {
+		v := query.Get("pretty")
+		return v != "false" && v != "0"
+	}
+	return config.APP().GO_ENV == "development"
+}
+
+// negotiation holds the rendering options negotiated for a request. It lives in
+// the request context, and the response helpers, which only receive the
+// http.ResponseWriter, find it through the requestWriter, looking through any
+// writer that wraps it and implements Unwrap.
+type negotiation struct {
+	r        *http.Request
+	problems ProblemMode
+	encoder  Encoder
+	pretty   bool
+}
+
+type negotiationKey struct{}
+
+// returns the negotiation of r and a request carrying it, creating it if needed
+func negotiate(r *http.Request) (*negotiation, *http.Request) {
+	if n, ok := r.Context().Value(negotiationKey{}).(*negotiation); ok {
+		return n, r
+	}
+	n := &negotiation{
+		problems: ProblemOnAccept,
+		encoder:  negotiateEncoder(r.Header.Get("Accept")),
+		pretty:   wantsPretty(r),
+	}
+	n.r = r.WithContext(context.WithValue(r.Context(), negotiationKey{}, n))
+	return n, n.r
+}
+
+// requestWriter makes the negotiation reachable from the http.ResponseWriter
+type requestWriter struct {
+	http.ResponseWriter
+	n *negotiation
+}
+
+func (w *requestWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// returns the negotiation carried by w or a writer it wraps, nil without one
+func negotiationOf(w http.ResponseWriter) *negotiation {
+	for {
+		switch v := w.(type) {
+		case *requestWriter:
+			return v.n
+		case interface{ Unwrap() http.ResponseWriter }:
+			w = v.Unwrap()
+		default:
+			return nil
+		}
+	}
+}
+
+// returns w and r carrying the negotiation of r
+func wrap(w http.ResponseWriter, r *http.Request) (http.ResponseWriter, *negotiation, *http.Request) {
+	n, r := negotiate(r)
+	if negotiationOf(w) != n {
+		w = &requestWriter{ResponseWriter: w, n: n}
+	}
+	return w, n, r
+}
+
+// Middleware that negotiates the response encoding from the Accept header
+// and responds 406 when none of the accepted media types is supported
+func Negotiate(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w, n, r := wrap(w, r)
+		w.Header().Add("Vary", "Accept")
+		if n.encoder == nil {
+			n.encoder = JSONEncoder
+			AbortWithStatusError(w, http.StatusNotAcceptable, "Not acceptable, supported types: "+supportedTypes())
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+func supportedTypes() string {
+	encodersMu.RLock()
+	defer encodersMu.RUnlock()
+	types := make([]string, len(encoders))
+	for i, e := range encoders {
+		types[i] = e.ContentType()
+	}
+	return strings.Join(types, ", ")
+}
+
+// a helper function to write a response in the negotiated encoding. The body is
+// buffered so that an encoding failure becomes a clean 500 instead of a half-written body.
+func writeJSON(w http.ResponseWriter, statusCode int, resp Response) {
+	writeEncoded(w, statusCode, resp, "")
+}
+
+// writes v with the negotiated encoder, contentType overrides the encoder's media type
+func writeEncoded(w http.ResponseWriter, statusCode int, v any, contentType string) {
+	enc, pretty := JSONEncoder, false
+	if n := negotiationOf(w); n != nil {
+		if n.encoder != nil {
+			enc = n.encoder
+		}
+		pretty = n.pretty
+	}
+	if contentType != "" {
+		enc = JSONEncoder
+	} else {
+		contentType = enc.ContentType()
+	}
+
+	var buf bytes.Buffer
+	if err := enc.Encode(&buf, v, pretty); err != nil {
+		logger.ErrorF("failed to encode %s response: %v", enc.ContentType(), err)
+		w.Header().Del("Location")
+		w.Header().Del("Link")
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusInternalServerError)
+		io.WriteString(w, `{"success":false,"message":"Internal server error"}`+"\n")
+		return
+	}
+
+	w.Header().Set("Content-Type", contentType)
+	w.WriteHeader(statusCode)
+	w.Write(buf.Bytes())
+}
@@ -0,0 +1,65 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestNegotiate(t *testing.T) {
+	tests := []struct {
+		accept      string
+		status      int
+		contentType string
+	}{
+		{"", http.StatusOK, "application/json"},
+		{"*/*", http.StatusOK, "application/json"},
+		{"application/msgpack", http.StatusOK, "application/msgpack"},
+		{"application/cbor;q=0.9, application/msgpack;q=0.5", http.StatusOK, "application/cbor"},
+		{"application/*;q=0.5, application/msgpack", http.StatusOK, "application/msgpack"},
+		{"application/problem+json", http.StatusOK, "application/json"},
+		{"text/html, application/json;q=0.1", http.StatusOK, "application/json"},
+		{"application/json;q=0, application/cbor", http.StatusOK, "application/cbor"},
+		{"text/html", http.StatusNotAcceptable, "application/json"},
+		{"application/json;q=0", http.StatusNotAcceptable, "application/json"},
+	}
+	handler := Negotiate(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		Success(w, "ok", map[string]int{"n": 0})
+	}))
+	for _, tt := range tests {
+		t.Run(tt.accept, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, "/?pretty=false", nil)
+			r.Header.Set("Accept", tt.accept)
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, r)
+			if rec.Code != tt.status {
+				t.Errorf("status %d, want %d", rec.Code, tt.status)
+			}
+			if got := rec.Header().Get("Content-Type"); got != tt.contentType {
+				t.Errorf("Content-Type %q, want %q", got, tt.contentType)
+			}
+			if got := rec.Header().Get("Vary"); got != "Accept" {
+				t.Errorf("Vary %q, want Accept", got)
+			}
+		})
+	}
+}
+
+// writers that wrap the negotiated one, like the OpenAPI response recorder, keep
+// the negotiated encoding when they implement Unwrap
+type unwrappingWriter struct{ http.ResponseWriter }
+
+func (w unwrappingWriter) Unwrap() http.ResponseWriter { return w.ResponseWriter }
+
+func TestNegotiateThroughWrappedWriter(t *testing.T) {
+	handler := Negotiate(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		Success(unwrappingWriter{w}, "ok", nil)
+	}))
+	r := httptest.NewRequest(http.MethodGet, "/?pretty=false", nil)
+	r.Header.Set("Accept", "application/cbor")
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, r)
+	if got := rec.Header().Get("Content-Type"); got != "application/cbor" {
+		t.Errorf("Content-Type %q, want application/cbor", got)
+	}
+}
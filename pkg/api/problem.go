@@ -0,0 +1,121 @@
+package api
+
+import (
+	"encoding/json"
+	"maps"
+	"net/http"
+	"strings"
+
+	chiMiddleware "github.com/go-chi/chi/v5/middleware"
+)
+
+const ProblemContentType = "application/problem+json"
+
+// Problem is an RFC 9457 problem details object. It implements error so that
+// handlers can return it and have it rendered by RenderError.
+type Problem struct {
+	Type     string `json:"type"`
+	Title    string `json:"title"`
+	Status   int    `json:"status"`
+	Detail   string `json:"detail,omitempty"`
+	Instance string `json:"instance,omitempty"`
+	// field errors keyed by JSON path, as returned by pkg.BindAndValidate
+	Errors map[string]string `json:"errors,omitempty"`
+	// additional extension members, rendered at the top level of the object
+	Extensions map[string]any `json:"-"`
+}
+
+// returns a problem for the status code with the default "about:blank" type
+func NewProblem(status int, detail string) *Problem {
+	return &Problem{
+		Type:   "about:blank",
+		Title:  http.StatusText(status),
+		Status: status,
+		Detail: detail,
+	}
+}
+
+func (p *Problem) Error() string {
+	if p.Detail != "" {
+		return p.Detail
+	}
+	return p.Title
+}
+
+func (p *Problem) MarshalJSON() ([]byte, error) {
+	type problem Problem
+	base, err := json.Marshal((*problem)(p))
+	if err != nil || len(p.Extensions) == 0 {
+		return base, err
+	}
+	members := map[string]any{}
+	maps.Copy(members, p.Extensions)
+	if err := json.Unmarshal(base, &members); err != nil {
+		return nil, err
+	}
+	return json.Marshal(members)
+}
+
+type ProblemMode int
+
+const (
+	// render problem+json only when the client sends it in the Accept header
+	ProblemOnAccept ProblemMode = iota
+	// always render errors as problem+json
+	ProblemAlways
+)
+
+// Middleware that opts the router into RFC 9457 problem+json error responses,
+// either always or when requested through the Accept header
+func ProblemDetails(mode ProblemMode) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w, n, r := wrap(w, r)
+			n.problems = mode
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// reports whether errors for r should be rendered as problem+json
+func wantsProblem(mode ProblemMode, r *http.Request) bool {
+	return mode == ProblemAlways || strings.Contains(r.Header.Get("Accept"), ProblemContentType)
+}
+
+// sends an error in the format negotiated for the request, either the default
+// Response envelope or a problem+json document. The opt-in is read from the
+// request's negotiation, found through any writers wrapping the requestWriter.
+func writeError(w http.ResponseWriter, p *Problem) {
+	if n := negotiationOf(w); n != nil && wantsProblem(n.problems, n.r) {
+		writeProblem(w, n.r, p)
+		return
+	}
+	writeJSON(w, p.Status, errorResponse(p.Detail, p.Errors))
+}
+
+// a helper function to write a problem+json response
+func writeProblem(w http.ResponseWriter, r *http.Request, p *Problem) {
+	doc := *p
+	if doc.Instance == "" {
+		doc.Instance = chiMiddleware.GetReqID(r.Context())
+	}
+	writeEncoded(w, doc.Status, &doc, ProblemContentType)
+}
+
+// renders an error returned by a handler: a *Problem or *HTTPError is sent with
+// its status, anything else is logged and becomes a 500 without exposing the message.
+// The problem+json opt-in comes from the request context, so it holds even when
+// w is wrapped by a writer that doesn't implement Unwrap.
+func RenderError(w http.ResponseWriter, r *http.Request, err error) {
+	w, _, r = wrap(w, r)
+	writeError(w, problemFor(r, err))
+}
+
+// builds the default error envelope, leaving out empty field errors
+func errorResponse(message string, fieldErrors map[string]string) Response {
+	resp := Response{Success: false, Message: message}
+	if len(fieldErrors) > 0 {
+		resp.Errors = fieldErrors
+	}
+	return resp
+}
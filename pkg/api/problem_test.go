@@ -0,0 +1,47 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestProblemDetails(t *testing.T) {
+	tests := []struct {
+		name        string
+		mode        ProblemMode
+		accept      string
+		contentType string
+	}{
+		{"envelope by default", ProblemOnAccept, "application/json", "application/json"},
+		{"problem when accepted", ProblemOnAccept, "application/problem+json", ProblemContentType},
+		{"problem always", ProblemAlways, "", ProblemContentType},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Negotiate runs first, as in main.go, so the opt-in is read from the request context
+			handler := Negotiate(ProblemDetails(tt.mode)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				NotFound(w, "User not found")
+			})))
+			r := httptest.NewRequest(http.MethodGet, "/users/1?pretty=false", nil)
+			r.Header.Set("Accept", tt.accept)
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, r)
+
+			if rec.Code != http.StatusNotFound {
+				t.Errorf("status %d, want 404", rec.Code)
+			}
+			if got := rec.Header().Get("Content-Type"); got != tt.contentType {
+				t.Errorf("Content-Type %q, want %q", got, tt.contentType)
+			}
+			var body map[string]any
+			if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+				t.Fatalf("decoding body: %v", err)
+			}
+			if tt.contentType == ProblemContentType && body["status"] != float64(http.StatusNotFound) {
+				t.Errorf("problem status %v, want 404", body["status"])
+			}
+		})
+	}
+}
@@ -0,0 +1,411 @@
+package pkg
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"go-rest-template/pkg/api"
+	"go-rest-template/pkg/config"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+	ut "github.com/go-playground/universal-translator"
+)
+
+const (
+	ContentTypeJSON      = "application/json"
+	ContentTypeForm      = "application/x-www-form-urlencoded"
+	ContentTypeMultipart = "multipart/form-data"
+)
+
+// BindOptions controls how BindAndValidateWith reads a request.
+type BindOptions struct {
+	// maximum body size in bytes, larger bodies get a 413. Zero uses MAX_BODY_BYTES
+	MaxBodyBytes int64
+	// reject JSON bodies with fields that are not in the target struct
+	DisallowUnknownFields bool
+	// accepted body content types, others get a 415
+	ContentTypes []string
+}
+
+// options used by BindAndValidate
+var DefaultBindOptions = BindOptions{
+	ContentTypes: []string{ContentTypeJSON, ContentTypeForm, ContentTypeMultipart},
+}
+
+var multipartFileHeaderType = reflect.TypeOf((*multipart.FileHeader)(nil))
+
+// decodes the request body into payload according to its Content-Type.
+// Field errors are returned for values that can't be converted to the field type,
+// in the language of the request.
+func bindBody(r *http.Request, payload any, opts BindOptions) (map[string]string, error) {
+	maxBytes := opts.MaxBodyBytes
+	if maxBytes <= 0 {
+		maxBytes = config.APP().MAX_BODY_BYTES
+	}
+	if r.Body == nil || r.Body == http.NoBody || r.ContentLength == 0 {
+		return nil, errEmptyBody
+	}
+
+	if len(opts.ContentTypes) == 0 {
+		opts.ContentTypes = DefaultBindOptions.ContentTypes
+	}
+	contentType, _, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if err != nil || !slices.Contains(opts.ContentTypes, contentType) {
+		return nil, api.ErrStatus(http.StatusUnsupportedMediaType,
+			"Unsupported Content-Type, expected one of: "+strings.Join(opts.ContentTypes, ", "))
+	}
+	r.Body = http.MaxBytesReader(nil, r.Body, maxBytes)
+
+	trans := TranslatorFor(r)
+	switch contentType {
+	case ContentTypeJSON:
+		return decodeJSON(r.Body, payload, opts, trans)
+	case ContentTypeForm:
+		if err := r.ParseForm(); err != nil {
+			return nil, bodyError(err)
+		}
+		return bindValues(payload, "form", trans, func(name string) []string { return r.PostForm[name] }, nil), nil
+	default:
+		if err := r.ParseMultipartForm(maxBytes); err != nil {
+			return nil, bodyError(err)
+		}
+		return bindValues(payload, "form", trans,
+			func(name string) []string { return r.MultipartForm.Value[name] },
+			func(name string) []*multipart.FileHeader { return r.MultipartForm.File[name] },
+		), nil
+	}
+}
+
+var errEmptyBody = api.ErrBadRequest("request body is empty")
+
+func decodeJSON(body io.Reader, payload any, opts BindOptions, trans ut.Translator) (map[string]string, error) {
+	dec := json.NewDecoder(body)
+	var raw json.RawMessage
+	if err := dec.Decode(&raw); err != nil {
+		if errors.Is(err, io.EOF) {
+			return nil, errEmptyBody
+		}
+		return nil, bodyError(err)
+	}
+	// the body must hold exactly one JSON value
+	if err := dec.Decode(&struct{}{}); !errors.Is(err, io.EOF) {
+		if err != nil {
+			return nil, bodyError(err)
+		}
+		return nil, api.ErrBadRequest("request body must contain a single JSON value")
+	}
+
+	if opts.DisallowUnknownFields {
+		var value any
+		if err := json.Unmarshal(raw, &value); err != nil {
+			return nil, bodyError(err)
+		}
+		if field := unknownField(value, reflect.TypeOf(payload), ""); field != "" {
+			return map[string]string{field: message(trans, "unknown_field")}, nil
+		}
+	}
+	if err := json.Unmarshal(raw, payload); err != nil {
+		var typeErr *json.UnmarshalTypeError
+		if errors.As(err, &typeErr) && typeErr.Field != "" {
+			return map[string]string{typeErr.Field: message(trans, typeTag(typeErr.Type.Kind()))}, nil
+		}
+		return nil, bodyError(err)
+	}
+	return nil, nil
+}
+
+var jsonUnmarshalerType = reflect.TypeOf((*json.Unmarshaler)(nil)).Elem()
+
+// returns the path of the first object key in value that has no field in t, matching
+// names case-insensitively like encoding/json, or "" when every key has a field.
+// Types with their own UnmarshalJSON are not looked into.
+func unknownField(value any, t reflect.Type, path string) string {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if reflect.PointerTo(t).Implements(jsonUnmarshalerType) {
+		return ""
+	}
+	switch v := value.(type) {
+	case map[string]any:
+		switch t.Kind() {
+		case reflect.Struct:
+			fields := jsonFields(t)
+			for key, item := range v {
+				ft, ok := fields[strings.ToLower(key)]
+				if !ok {
+					return joinField(path, key)
+				}
+				if field := unknownField(item, ft, joinField(path, key)); field != "" {
+					return field
+				}
+			}
+		case reflect.Map:
+			for key, item := range v {
+				if field := unknownField(item, t.Elem(), joinField(path, key)); field != "" {
+					return field
+				}
+			}
+		}
+	case []any:
+		if t.Kind() == reflect.Slice || t.Kind() == reflect.Array {
+			for i, item := range v {
+				if field := unknownField(item, t.Elem(), fmt.Sprintf("%s[%d]", path, i)); field != "" {
+					return field
+				}
+			}
+		}
+	}
+	return ""
+}
+
+// returns the types of the JSON fields of a struct keyed by lower-cased name,
+// including the fields of embedded structs
+func jsonFields(t reflect.Type) map[string]reflect.Type {
+	fields := map[string]reflect.Type{}
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		name, _, _ := strings.Cut(f.Tag.Get("json"), ",")
+		if name == "-" {
+			continue
+		}
+		ft := f.Type
+		for ft.Kind() == reflect.Ptr {
+			ft = ft.Elem()
+		}
+		if f.Anonymous && name == "" && ft.Kind() == reflect.Struct {
+			for k, v := range jsonFields(ft) {
+				if _, exists := fields[k]; !exists {
+					fields[k] = v
+				}
+			}
+			continue
+		}
+		if !f.IsExported() {
+			continue
+		}
+		if name == "" {
+			name = f.Name
+		}
+		fields[strings.ToLower(name)] = f.Type
+	}
+	return fields
+}
+
+func joinField(path, name string) string {
+	if path == "" {
+		return name
+	}
+	return path + "." + name
+}
+
+// maps body read errors to 413 for oversized bodies and 400 otherwise
+func bodyError(err error) error {
+	var maxErr *http.MaxBytesError
+	if errors.As(err, &maxErr) {
+		return api.ErrStatus(http.StatusRequestEntityTooLarge, fmt.Sprintf("request body must not be larger than %d bytes", maxErr.Limit))
+	}
+	return api.ErrBadRequest(err.Error())
+}
+
+// binds the query string and chi path parameters to the fields tagged `query` and `path`
+func bindParams(r *http.Request, payload any) map[string]string {
+	query := r.URL.Query()
+	trans := TranslatorFor(r)
+	errs := bindValues(payload, "query", trans, func(name string) []string { return query[name] }, nil)
+	pathErrs := bindValues(payload, "path", trans, func(name string) []string {
+		if v := chi.URLParam(r, name); v != "" {
+			return []string{v}
+		}
+		return nil
+	}, nil)
+	for k, v := range pathErrs {
+		if errs == nil {
+			errs = map[string]string{}
+		}
+		errs[k] = v
+	}
+	return errs
+}
+
+// reports whether the struct has fields bound from the query string or path
+func hasParamFields(t reflect.Type) bool {
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t.Kind() != reflect.Struct {
+		return false
+	}
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if f.Anonymous && hasParamFields(f.Type) {
+			return true
+		}
+		if f.Tag.Get("query") != "" || f.Tag.Get("path") != "" {
+			return true
+		}
+	}
+	return false
+}
+
+// sets the struct fields carrying the given tag from the lookup functions.
+// `form` falls back to the json tag so one struct serves JSON and form bodies.
+func bindValues(payload any, tag string, trans ut.Translator, values func(string) []string, files func(string) []*multipart.FileHeader) map[string]string {
+	v := reflect.ValueOf(payload)
+	if v.Kind() == reflect.Ptr {
+		v = v.Elem()
+	}
+	if v.Kind() != reflect.Struct {
+		return nil
+	}
+
+	var errs map[string]string
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		field, fv := t.Field(i), v.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+		if field.Anonymous && fv.Kind() == reflect.Struct {
+			for k, msg := range bindValues(fv.Addr().Interface(), tag, trans, values, files) {
+				if errs == nil {
+					errs = map[string]string{}
+				}
+				errs[k] = msg
+			}
+			continue
+		}
+
+		name := tagName(field, tag)
+		if name == "" {
+			continue
+		}
+
+		if files != nil && (field.Type == multipartFileHeaderType || field.Type == reflect.SliceOf(multipartFileHeaderType)) {
+			if fhs := files(name); len(fhs) > 0 {
+				if field.Type.Kind() == reflect.Slice {
+					fv.Set(reflect.ValueOf(fhs))
+				} else {
+					fv.Set(reflect.ValueOf(fhs[0]))
+				}
+			}
+			continue
+		}
+
+		raw := values(name)
+		if len(raw) == 0 {
+			continue
+		}
+		if err := setField(fv, raw); err != nil {
+			if errs == nil {
+				errs = map[string]string{}
+			}
+			errs[name] = message(trans, err.Error())
+		}
+	}
+	return errs
+}
+
+func tagName(field reflect.StructField, tag string) string {
+	name := field.Tag.Get(tag)
+	if name == "" && tag == "form" {
+		name = field.Tag.Get("json")
+	}
+	name, _, _ = strings.Cut(name, ",")
+	if name == "-" {
+		return ""
+	}
+	return name
+}
+
+// returns the message tag for a value that doesn't fit a field of the kind
+func typeTag(kind reflect.Kind) string {
+	switch kind {
+	case reflect.Bool:
+		return "boolean"
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		return "number"
+	case reflect.String:
+		return "type_string"
+	case reflect.Slice, reflect.Array:
+		return "type_array"
+	case reflect.Struct, reflect.Map:
+		return "type_object"
+	default:
+		return "invalid"
+	}
+}
+
+// returns the message of tag in the translator's locale
+func message(trans ut.Translator, tag string) string {
+	msg, err := translate(trans, tag)
+	if err != nil {
+		return tag
+	}
+	return msg
+}
+
+// converts raw string values to the field type. The error text is the message tag.
+func setField(fv reflect.Value, raw []string) error {
+	if fv.Kind() == reflect.Ptr {
+		ptr := reflect.New(fv.Type().Elem())
+		if err := setField(ptr.Elem(), raw); err != nil {
+			return err
+		}
+		fv.Set(ptr)
+		return nil
+	}
+	if fv.Kind() == reflect.Slice && fv.Type().Elem().Kind() != reflect.Uint8 {
+		slice := reflect.MakeSlice(fv.Type(), len(raw), len(raw))
+		for i, s := range raw {
+			if err := setField(slice.Index(i), []string{s}); err != nil {
+				return err
+			}
+		}
+		fv.Set(slice)
+		return nil
+	}
+
+	s := raw[0]
+	switch fv.Kind() {
+	case reflect.String:
+		fv.SetString(s)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(s)
+		if err != nil {
+			return errors.New(typeTag(reflect.Bool))
+		}
+		fv.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := strconv.ParseInt(s, 10, fv.Type().Bits())
+		if err != nil {
+			return errors.New(typeTag(fv.Kind()))
+		}
+		fv.SetInt(n)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n, err := strconv.ParseUint(s, 10, fv.Type().Bits())
+		if err != nil {
+			return errors.New(typeTag(fv.Kind()))
+		}
+		fv.SetUint(n)
+	case reflect.Float32, reflect.Float64:
+		n, err := strconv.ParseFloat(s, fv.Type().Bits())
+		if err != nil {
+			return errors.New(typeTag(fv.Kind()))
+		}
+		fv.SetFloat(n)
+	default:
+		return errors.New("invalid")
+	}
+	return nil
+}
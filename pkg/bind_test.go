@@ -0,0 +1,86 @@
+package pkg
+
+import (
+	"maps"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+type bindAddress struct {
+	City string `json:"city"`
+}
+
+type bindBase struct {
+	ID int `json:"id"`
+}
+
+type bindPayload struct {
+	bindBase
+	Name    string        `json:"name"`
+	Age     int           `json:"age"`
+	Address bindAddress   `json:"address"`
+	Tags    []bindAddress `json:"tags"`
+	Secret  string        `json:"-"`
+}
+
+func TestBindUnknownFields(t *testing.T) {
+	opts := BindOptions{MaxBodyBytes: 1 << 10, ContentTypes: []string{ContentTypeJSON}, DisallowUnknownFields: true}
+	tests := []struct {
+		name     string
+		body     string
+		language string
+		want     map[string]string
+	}{
+		{"known fields", `{"id":1,"NAME":"a","address":{"City":"b"},"tags":[{"city":"c"}]}`, "", nil},
+		{"top level", `{"name":"a","extra":1}`, "", map[string]string{"extra": "Unknown field"}},
+		{"nested object", `{"address":{"zip":"1"}}`, "", map[string]string{"address.zip": "Unknown field"}},
+		{"array item", `{"tags":[{"city":"c"},{"street":"s"}]}`, "", map[string]string{"tags[1].street": "Unknown field"}},
+		{"ignored field", `{"Secret":"s"}`, "", map[string]string{"Secret": "Unknown field"}},
+		{"localized", `{"extra":1}`, "fr", map[string]string{"extra": "Champ inconnu"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest("POST", "/", strings.NewReader(tt.body))
+			r.Header.Set("Content-Type", ContentTypeJSON)
+			r.Header.Set("Accept-Language", tt.language)
+			_, errs, err := BindAndValidateWith[bindPayload](r, opts)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !maps.Equal(errs, tt.want) {
+				t.Errorf("got %v, want %v", errs, tt.want)
+			}
+		})
+	}
+}
+
+func TestBindTypeMessages(t *testing.T) {
+	opts := BindOptions{MaxBodyBytes: 1 << 10}
+	tests := []struct {
+		name        string
+		contentType string
+		body        string
+		language    string
+		want        map[string]string
+	}{
+		{"json number", ContentTypeJSON, `{"age":"x"}`, "", map[string]string{"age": "Must be a valid number"}},
+		{"json string", ContentTypeJSON, `{"name":1}`, "es", map[string]string{"name": "Debe ser una cadena"}},
+		{"json object", ContentTypeJSON, `{"address":[]}`, "", map[string]string{"address": "Must be an object"}},
+		{"form number", ContentTypeForm, `age=x`, "fr", map[string]string{"age": "Doit être un nombre valide"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest("POST", "/", strings.NewReader(tt.body))
+			r.Header.Set("Content-Type", tt.contentType)
+			r.Header.Set("Accept-Language", tt.language)
+			_, errs, err := BindAndValidateWith[bindPayload](r, opts)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !maps.Equal(errs, tt.want) {
+				t.Errorf("got %v, want %v", errs, tt.want)
+			}
+		})
+	}
+}
@@ -1,69 +1,89 @@
 package config
 
 import (
-	"os"
-	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
 )
 
-type conf struct {
-	PORT             uint
-	JWT_TOKEN        string
-	DB_URL           string
-	GO_ENV           string
-	DEBUG            bool
-	MIGRATE_ON_START bool
-	API_URL          string
-	CLIENT_URL       string
-	COOKIE_DOMAIN    string
-	COOKIE_AGE_HOURS int
+// Config is the application configuration. Each field is read from, in increasing
+// priority, its default, the config file (lowercase key), the environment (the `env`
+// name, or a file named by `<env>_FILE`) and the command line (lowercase, dashed flag).
+// Fields tagged `secret` are redacted when printed, fields tagged `reload:"restart"`
+// keep their running value when the configuration is reloaded.
+type Config struct {
+	PORT                        uint          `env:"PORT" default:"8080" reload:"restart"`
+	JWT_TOKEN                   string        `env:"JWT_TOKEN" secret:"true"`
+	DB_URL                      string        `env:"DB_URL" secret:"true" reload:"restart"`
+	GO_ENV                      string        `env:"GO_ENV" default:"development" reload:"restart"`
+	DEBUG                       bool          `env:"DEBUG" default:"false"`
+	LOG_LEVEL                   string        `env:"LOG_LEVEL"`
+	LOG_OUTPUTS                 []string      `env:"LOG_OUTPUTS" default:"stdout" reload:"restart"`
+	LOG_SAMPLE_DEBUG            uint          `env:"LOG_SAMPLE_DEBUG" default:"1" reload:"restart"`
+	LOG_REDACT_KEYS             []string      `env:"LOG_REDACT_KEYS" reload:"restart"`
+	MIGRATE_ON_START            bool          `env:"MIGRATE_ON_START" default:"false" reload:"restart"`
+	API_URL                     string        `env:"API_URL"`
+	CLIENT_URL                  string        `env:"CLIENT_URL"`
+	COOKIE_DOMAIN               string        `env:"COOKIE_DOMAIN"`
+	COOKIE_AGE_HOURS            int           `env:"COOKIE_AGE_HOURS" default:"24"`
+	MAX_BODY_BYTES              int64         `env:"MAX_BODY_BYTES" default:"1048576"`
+	TLS_CERT_FILE               string        `env:"TLS_CERT_FILE" reload:"restart"`
+	TLS_KEY_FILE                string        `env:"TLS_KEY_FILE" reload:"restart"`
+	TLS_CLIENT_CA_FILE          string        `env:"TLS_CLIENT_CA_FILE" reload:"restart"`
+	TLS_CLIENT_AUTH             string        `env:"TLS_CLIENT_AUTH" default:"none" reload:"restart"`
+	TLS_MIN_VERSION             string        `env:"TLS_MIN_VERSION" default:"1.2" reload:"restart"`
+	TLS_REDIRECT_ADDR           string        `env:"TLS_REDIRECT_ADDR" reload:"restart"`
+	CORS_ALLOWED_ORIGINS        []string      `env:"CORS_ALLOWED_ORIGINS"`
+	CORS_ALLOWED_METHODS        []string      `env:"CORS_ALLOWED_METHODS" default:"GET,POST,PUT,DELETE"`
+	CORS_ALLOWED_HEADERS        []string      `env:"CORS_ALLOWED_HEADERS" default:"Accept,Authorization,Content-Type,X-CSRF-Token"`
+	CORS_EXPOSED_HEADERS        []string      `env:"CORS_EXPOSED_HEADERS"`
+	CORS_ALLOW_CREDENTIALS      bool          `env:"CORS_ALLOW_CREDENTIALS" default:"false"`
+	CORS_MAX_AGE                time.Duration `env:"CORS_MAX_AGE" default:"5m"`
+	HSTS_MAX_AGE                time.Duration `env:"HSTS_MAX_AGE" default:"8760h"`
+	SECURITY_CSP                string        `env:"SECURITY_CSP" default:"default-src 'none'; frame-ancestors 'none'"`
+	SECURITY_CSP_REPORT_ONLY    bool          `env:"SECURITY_CSP_REPORT_ONLY" default:"false"`
+	SECURITY_FRAME_OPTIONS      string        `env:"SECURITY_FRAME_OPTIONS" default:"DENY"`
+	SECURITY_REFERRER_POLICY    string        `env:"SECURITY_REFERRER_POLICY" default:"no-referrer"`
+	SECURITY_PERMISSIONS_POLICY string        `env:"SECURITY_PERMISSIONS_POLICY" default:"camera=(), microphone=(), geolocation=(), payment=()"`
+	SECURITY_COOP               string        `env:"SECURITY_COOP" default:"same-origin"`
+	SECURITY_COEP               string        `env:"SECURITY_COEP" default:"require-corp"`
+	SECURITY_CORP               string        `env:"SECURITY_CORP" default:"same-site"`
+	TRUSTED_PROXIES             []string      `env:"TRUSTED_PROXIES" reload:"restart"`
+	HTTP_READ_TIMEOUT           time.Duration `env:"HTTP_READ_TIMEOUT" default:"15s" reload:"restart"`
+	HTTP_READ_HEADER_TIMEOUT    time.Duration `env:"HTTP_READ_HEADER_TIMEOUT" default:"5s" reload:"restart"`
+	HTTP_WRITE_TIMEOUT          time.Duration `env:"HTTP_WRITE_TIMEOUT" default:"30s" reload:"restart"`
+	HTTP_IDLE_TIMEOUT           time.Duration `env:"HTTP_IDLE_TIMEOUT" default:"120s" reload:"restart"`
+	HTTP_MAX_HEADER_BYTES       int           `env:"HTTP_MAX_HEADER_BYTES" default:"1048576" reload:"restart"`
+	SHUTDOWN_DRAIN              time.Duration `env:"SHUTDOWN_DRAIN" default:"5s" reload:"restart"`
+	SHUTDOWN_TIMEOUT            time.Duration `env:"SHUTDOWN_TIMEOUT" default:"20s" reload:"restart"`
+	HEALTH_CHECK_TIMEOUT        time.Duration `env:"HEALTH_CHECK_TIMEOUT" default:"2s" reload:"restart"`
+	HEALTH_CACHE_TTL            time.Duration `env:"HEALTH_CACHE_TTL" default:"2s"`
+	METRICS_ADDR                string        `env:"METRICS_ADDR" default:":9090" reload:"restart"`
+	TRACE_EXPORTER              string        `env:"TRACE_EXPORTER" default:"none" reload:"restart"`
+	TRACE_SAMPLE_RATIO          float64       `env:"TRACE_SAMPLE_RATIO" default:"1" reload:"restart"`
 }
 
-func APP() conf {
-	return conf{
-		PORT:      uint(getEnvInt("PORT", 8080)),
-		JWT_TOKEN: getEnv("JWT_TOKEN", ""),
-		DB_URL:    getEnv("DB_URL", ""),
-		GO_ENV:    getEnv("GO_ENV", "development"),
-		DEBUG:     getEnvBool("DEBUG", false),
-
-		MIGRATE_ON_START: getEnvBool("MIGRATE_ON_START", false),
-		API_URL:          getEnv("API_URL", ""),
-		CLIENT_URL:       getEnv("CLIENT_URL", ""),
-		COOKIE_DOMAIN:    getEnv("COOKIE_DOMAIN", ""),
-		COOKIE_AGE_HOURS: getEnvInt("COOKIE_AGE_HOURS", 24),
-	}
-}
-
-func getEnv(key string, defaultValue string) string {
-	value, exists := os.LookupEnv(key)
-	if !exists {
-		return defaultValue
-	}
-	return value
-
-}
-
-func getEnvInt(key string, defaultValue int) int {
-	value, exists := os.LookupEnv(key)
-	if !exists {
-		return defaultValue
-	}
-	intValue, err := strconv.Atoi(value)
-	if err != nil {
-		return defaultValue
-	}
-	return intValue
-
-}
+var (
+	current  atomic.Pointer[Config]
+	loadOnce sync.Once
+)
 
-func getEnvBool(key string, defaultValue bool) bool {
-	value, exists := os.LookupEnv(key)
-	if !exists {
-		return defaultValue
-	}
-	boolValue, err := strconv.ParseBool(value)
-	if err != nil {
-		return defaultValue
+// returns the loaded configuration. When Load has not been called yet, the
+// configuration is loaded from the config file and environment without flags,
+// which panics on invalid values.
+func APP() Config {
+	if c := current.Load(); c != nil {
+		return *c
 	}
-	return boolValue
+	loadOnce.Do(func() {
+		if current.Load() != nil {
+			return
+		}
+		c, err := load(nil, nil)
+		if err != nil {
+			panic(err)
+		}
+		current.Store(c)
+	})
+	return *current.Load()
 }
@@ -0,0 +1,303 @@
+package config
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/BurntSushi/toml"
+	"github.com/joho/godotenv"
+	"gopkg.in/yaml.v3"
+)
+
+// field describes one configuration value and where it is read from
+type field struct {
+	env    string
+	key    string
+	flag   string
+	def    string
+	secret bool
+	// the running value is kept on reload, changes need a restart
+	restart bool
+	value   reflect.Value
+}
+
+// lists the fields of c in declaration order
+func fields(c *Config) []field {
+	v := reflect.ValueOf(c).Elem()
+	t := v.Type()
+	list := make([]field, 0, t.NumField())
+	for i := range t.NumField() {
+		sf := t.Field(i)
+		env := sf.Tag.Get("env")
+		if env == "" {
+			continue
+		}
+		list = append(list, field{
+			env:     env,
+			key:     strings.ToLower(env),
+			flag:    strings.ReplaceAll(strings.ToLower(env), "_", "-"),
+			def:     sf.Tag.Get("default"),
+			secret:  sf.Tag.Get("secret") == "true",
+			restart: sf.Tag.Get("reload") == "restart",
+			value:   v.Field(i),
+		})
+	}
+	return list
+}
+
+// Load builds the configuration from defaults, the config file, the environment and
+// the flags in args, registering a flag for every field plus -config on fs. The file
+// is named by -config or CONFIG_FILE and may be YAML (.yaml, .yml) or TOML (.toml).
+// All invalid values are reported together. On success the configuration becomes the
+// one returned by APP; it is not validated, see Validate.
+func Load(fs *flag.FlagSet, args []string) (*Config, error) {
+	c, err := load(fs, args)
+	if err != nil {
+		return nil, err
+	}
+	current.Store(c)
+	return c, nil
+}
+
+// sources remembers the config file and flags a configuration was loaded from
+// so that Reload can build it again
+type sources struct {
+	file  string
+	flags map[string]string
+}
+
+var loadedFrom atomic.Pointer[sources]
+
+func load(fs *flag.FlagSet, args []string) (*Config, error) {
+	// values from .env are added to the environment without overriding it
+	if err := godotenv.Load(); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("reading .env: %w", err)
+	}
+
+	src := &sources{file: os.Getenv("CONFIG_FILE"), flags: map[string]string{}}
+	if fs != nil {
+		fs.StringVar(&src.file, "config", src.file, "config file (YAML or TOML)")
+		for _, f := range fields(&Config{}) {
+			fs.Var(&flagValue{kind: f.value.Kind(), name: f.flag, values: src.flags}, f.flag, "overrides "+f.env)
+		}
+		if err := fs.Parse(args); err != nil {
+			return nil, err
+		}
+	}
+
+	c, err := build(src)
+	if err != nil {
+		return nil, err
+	}
+	loadedFrom.Store(src)
+	return c, nil
+}
+
+// builds a configuration from defaults and the given sources
+func build(src *sources) (*Config, error) {
+	c := &Config{}
+	list := fields(c)
+	var errs []error
+
+	for _, f := range list {
+		if f.def != "" {
+			errs = append(errs, set(f, f.def, "default"))
+		}
+	}
+
+	if src.file != "" {
+		values, err := readFile(src.file)
+		if err != nil {
+			return nil, err
+		}
+		byKey := map[string]field{}
+		for _, f := range list {
+			byKey[f.key] = f
+		}
+		for key, value := range values {
+			f, ok := byKey[key]
+			if !ok {
+				errs = append(errs, fmt.Errorf("%s: unknown key %q", src.file, key))
+				continue
+			}
+			s, err := fileValue(value)
+			if err != nil {
+				errs = append(errs, fmt.Errorf("%s (%s): %w", f.env, src.file, err))
+				continue
+			}
+			errs = append(errs, set(f, s, src.file))
+		}
+	}
+
+	for _, f := range list {
+		value, ok := os.LookupEnv(f.env)
+		file, fromFile := os.LookupEnv(f.env + "_FILE")
+		switch {
+		case ok && fromFile:
+			errs = append(errs, fmt.Errorf("%s: both %s and %s_FILE are set", f.env, f.env, f.env))
+		case fromFile:
+			content, err := os.ReadFile(file)
+			if err != nil {
+				errs = append(errs, fmt.Errorf("%s_FILE: %w", f.env, err))
+				continue
+			}
+			errs = append(errs, set(f, strings.TrimRight(string(content), "\r\n"), f.env+"_FILE"))
+		case ok:
+			errs = append(errs, set(f, value, "env"))
+		}
+	}
+
+	for _, f := range list {
+		if value, ok := src.flags[f.flag]; ok {
+			errs = append(errs, set(f, value, "flag -"+f.flag))
+		}
+	}
+
+	if err := errors.Join(errs...); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// reads a YAML or TOML config file into a map of top-level keys, rejecting unknown syntax
+func readFile(path string) (map[string]any, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading config file: %w", err)
+	}
+	values := map[string]any{}
+	switch ext := strings.ToLower(filepath.Ext(path)); ext {
+	case ".yaml", ".yml":
+		if err := yaml.Unmarshal(content, &values); err != nil {
+			return nil, fmt.Errorf("parsing %s: %w", path, err)
+		}
+	case ".toml":
+		if err := toml.Unmarshal(content, &values); err != nil {
+			return nil, fmt.Errorf("parsing %s: %w", path, err)
+		}
+	default:
+		return nil, fmt.Errorf("config file %s: unsupported extension %q, use .yaml, .yml or .toml", path, ext)
+	}
+	return values, nil
+}
+
+// converts a value decoded from a config file to the string form used by env and flags
+func fileValue(value any) (string, error) {
+	switch v := value.(type) {
+	case nil:
+		return "", nil
+	case []any:
+		items := make([]string, len(v))
+		for i, item := range v {
+			s, err := fileValue(item)
+			if err != nil {
+				return "", err
+			}
+			items[i] = s
+		}
+		return strings.Join(items, ","), nil
+	case map[string]any:
+		return "", errors.New("must be a scalar or a list")
+	default:
+		return fmt.Sprint(v), nil
+	}
+}
+
+var durationType = reflect.TypeOf(time.Duration(0))
+
+// parses s into the field, naming the source in the error
+func set(f field, s, source string) error {
+	v := f.value
+	var err error
+	switch {
+	case v.Type() == durationType:
+		var d time.Duration
+		d, err = time.ParseDuration(s)
+		v.SetInt(int64(d))
+	case v.Kind() == reflect.String:
+		v.SetString(s)
+	case v.Kind() == reflect.Bool:
+		var b bool
+		b, err = strconv.ParseBool(s)
+		v.SetBool(b)
+	case v.CanInt():
+		var n int64
+		n, err = strconv.ParseInt(s, 10, v.Type().Bits())
+		v.SetInt(n)
+	case v.CanFloat():
+		var n float64
+		n, err = strconv.ParseFloat(s, v.Type().Bits())
+		v.SetFloat(n)
+	case v.CanUint():
+		var n uint64
+		n, err = strconv.ParseUint(s, 10, v.Type().Bits())
+		v.SetUint(n)
+	case v.Kind() == reflect.Slice && v.Type().Elem().Kind() == reflect.String:
+		var items []string
+		for item := range strings.SplitSeq(s, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				items = append(items, item)
+			}
+		}
+		v.Set(reflect.ValueOf(items))
+	default:
+		err = fmt.Errorf("unsupported type %s", v.Type())
+	}
+	if err != nil {
+		shown := s
+		if f.secret {
+			shown = redacted
+		}
+		return fmt.Errorf("%s: invalid value %q from %s, expected %s", f.env, shown, source, typeName(v.Type()))
+	}
+	return nil
+}
+
+func typeName(t reflect.Type) string {
+	switch {
+	case t == durationType:
+		return "a duration like 30s"
+	case t.Kind() == reflect.Bool:
+		return "a boolean"
+	case t.Kind() >= reflect.Int && t.Kind() <= reflect.Int64:
+		return "an integer"
+	case t.Kind() >= reflect.Uint && t.Kind() <= reflect.Uint64:
+		return "a non-negative integer"
+	case t.Kind() == reflect.Float32 || t.Kind() == reflect.Float64:
+		return "a number"
+	case t.Kind() == reflect.Slice:
+		return "a comma separated list"
+	}
+	return t.String()
+}
+
+// flagValue records the raw value of a flag so it can be applied after the other sources
+type flagValue struct {
+	kind   reflect.Kind
+	name   string
+	values map[string]string
+}
+
+func (f *flagValue) String() string {
+	if f == nil || f.values == nil {
+		return ""
+	}
+	return f.values[f.name]
+}
+
+func (f *flagValue) Set(s string) error {
+	f.values[f.name] = s
+	return nil
+}
+
+func (f *flagValue) IsBoolFlag() bool {
+	return f.kind == reflect.Bool
+}
@@ -0,0 +1,53 @@
+package config
+
+import (
+	"io"
+	"reflect"
+
+	"gopkg.in/yaml.v3"
+)
+
+const redacted = "[REDACTED]"
+
+// Redacted returns the configuration keyed by config file keys, with secrets
+// that are set replaced by a placeholder
+func (c *Config) Redacted() map[string]any {
+	values := map[string]any{}
+	for _, f := range fields(c) {
+		values[f.key] = redactedValue(f)
+	}
+	return values
+}
+
+func redactedValue(f field) any {
+	if f.secret && !f.value.IsZero() {
+		return redacted
+	}
+	return f.value.Interface()
+}
+
+// Print writes the configuration as YAML with secrets redacted, in a form that
+// can be used as a config file
+func (c *Config) Print(w io.Writer) error {
+	doc := &yaml.Node{Kind: yaml.MappingNode}
+	for _, f := range fields(c) {
+		var value yaml.Node
+		v := redactedValue(f)
+		if d, ok := v.(interface{ String() string }); ok && f.value.Type() == durationType {
+			v = d.String()
+		}
+		if err := value.Encode(v); err != nil {
+			return err
+		}
+		if f.value.Kind() == reflect.Slice && f.value.Len() == 0 {
+			value = yaml.Node{Kind: yaml.SequenceNode, Style: yaml.FlowStyle}
+		}
+		doc.Content = append(doc.Content, &yaml.Node{Kind: yaml.ScalarNode, Value: f.key}, &value)
+	}
+	enc := yaml.NewEncoder(w)
+	enc.SetIndent(2)
+	if err := enc.Encode(doc); err != nil {
+		return err
+	}
+	return enc.Close()
+}
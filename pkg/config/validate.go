@@ -0,0 +1,176 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"net/netip"
+	"net/url"
+	"slices"
+	"strings"
+	"time"
+)
+
+// minimum length of JWT_TOKEN in production
+const minSecretLength = 32
+
+var environments = []string{"development", "test", "production"}
+
+var logOutputs = []string{"stdout", "stderr", "file", "udp"}
+
+var traceExporters = []string{"none", "otlp", "stdout"}
+
+var tlsClientAuthModes = []string{"none", "optional", "require"}
+
+var tlsMinVersions = []string{"1.2", "1.3"}
+
+var frameOptions = []string{"", "DENY", "SAMEORIGIN"}
+
+var logLevels = []string{"", "trace", "debug", "info", "warn", "error", "fatal", "panic", "disabled"}
+
+// Validate checks required values and, when GO_ENV is production, that the
+// configuration is safe to run in production. All problems are reported together.
+func (c *Config) Validate() error {
+	var errs []error
+	add := func(format string, args ...any) {
+		errs = append(errs, fmt.Errorf(format, args...))
+	}
+
+	if c.JWT_TOKEN == "" {
+		add("JWT_TOKEN is required")
+	}
+	if c.DB_URL == "" {
+		add("DB_URL is required")
+	}
+	if c.PORT == 0 || c.PORT > 65535 {
+		add("PORT must be between 1 and 65535, got %d", c.PORT)
+	}
+	if !slices.Contains(environments, c.GO_ENV) {
+		add("GO_ENV must be one of %v, got %q", environments, c.GO_ENV)
+	}
+	if !slices.Contains(logLevels, c.LOG_LEVEL) {
+		add("LOG_LEVEL must be one of %v, got %q", logLevels[1:], c.LOG_LEVEL)
+	}
+	if len(c.LOG_OUTPUTS) == 0 {
+		add("LOG_OUTPUTS must name at least one output")
+	}
+	for _, output := range c.LOG_OUTPUTS {
+		u, err := url.Parse(output)
+		target := ""
+		if err == nil {
+			target = u.Scheme
+			if target == "" {
+				target = u.Path
+			}
+		}
+		if !slices.Contains(logOutputs, target) {
+			add("LOG_OUTPUTS: %q must be stdout, stderr, a file:// or a udp:// URI", output)
+		}
+	}
+	if c.LOG_SAMPLE_DEBUG == 0 {
+		add("LOG_SAMPLE_DEBUG must be at least 1")
+	}
+	if u, err := url.Parse(c.TRACE_EXPORTER); !slices.Contains(traceExporters, c.TRACE_EXPORTER) && (err != nil || u.Scheme != "file") {
+		add("TRACE_EXPORTER must be one of %v or a file:// URI, got %q", traceExporters, c.TRACE_EXPORTER)
+	}
+	if c.TRACE_SAMPLE_RATIO < 0 || c.TRACE_SAMPLE_RATIO > 1 {
+		add("TRACE_SAMPLE_RATIO must be between 0 and 1, got %v", c.TRACE_SAMPLE_RATIO)
+	}
+	if (c.TLS_CERT_FILE == "") != (c.TLS_KEY_FILE == "") {
+		add("TLS_CERT_FILE and TLS_KEY_FILE must be set together")
+	}
+	if !slices.Contains(tlsClientAuthModes, c.TLS_CLIENT_AUTH) {
+		add("TLS_CLIENT_AUTH must be one of %v, got %q", tlsClientAuthModes, c.TLS_CLIENT_AUTH)
+	} else if c.TLS_CLIENT_AUTH != "none" && c.TLS_CLIENT_CA_FILE == "" {
+		add("TLS_CLIENT_CA_FILE is required when TLS_CLIENT_AUTH is %s", c.TLS_CLIENT_AUTH)
+	}
+	if c.TLS_CLIENT_AUTH != "none" && c.TLS_CERT_FILE == "" {
+		add("TLS_CLIENT_AUTH needs TLS_CERT_FILE and TLS_KEY_FILE")
+	}
+	if !slices.Contains(tlsMinVersions, c.TLS_MIN_VERSION) {
+		add("TLS_MIN_VERSION must be one of %v, got %q", tlsMinVersions, c.TLS_MIN_VERSION)
+	}
+	if c.TLS_REDIRECT_ADDR != "" && c.TLS_CERT_FILE == "" {
+		add("TLS_REDIRECT_ADDR needs TLS_CERT_FILE and TLS_KEY_FILE")
+	}
+	if c.HSTS_MAX_AGE < 0 {
+		add("HSTS_MAX_AGE must not be negative, got %s", c.HSTS_MAX_AGE)
+	}
+	for _, origin := range c.CORS_ALLOWED_ORIGINS {
+		if !validOrigin(origin) {
+			add("CORS_ALLOWED_ORIGINS must contain origins like https://app.example.com or https://*.example.com, got %q", origin)
+		}
+	}
+	if c.CORS_ALLOW_CREDENTIALS && slices.Contains(c.CORS_ALLOWED_ORIGINS, "*") {
+		add("CORS_ALLOWED_ORIGINS must not contain * when CORS_ALLOW_CREDENTIALS is on")
+	}
+	if c.CORS_MAX_AGE < 0 {
+		add("CORS_MAX_AGE must not be negative, got %s", c.CORS_MAX_AGE)
+	}
+	for _, proxy := range c.TRUSTED_PROXIES {
+		if _, err := netip.ParsePrefix(proxy); err != nil {
+			if _, err := netip.ParseAddr(proxy); err != nil {
+				add("TRUSTED_PROXIES must contain IPs or CIDR ranges, got %q", proxy)
+			}
+		}
+	}
+	if !slices.Contains(frameOptions, c.SECURITY_FRAME_OPTIONS) {
+		add("SECURITY_FRAME_OPTIONS must be one of %q, got %q", frameOptions, c.SECURITY_FRAME_OPTIONS)
+	}
+	for name, d := range map[string]time.Duration{
+		"HTTP_READ_TIMEOUT":        c.HTTP_READ_TIMEOUT,
+		"HTTP_READ_HEADER_TIMEOUT": c.HTTP_READ_HEADER_TIMEOUT,
+		"HTTP_WRITE_TIMEOUT":       c.HTTP_WRITE_TIMEOUT,
+		"HTTP_IDLE_TIMEOUT":        c.HTTP_IDLE_TIMEOUT,
+		"SHUTDOWN_TIMEOUT":         c.SHUTDOWN_TIMEOUT,
+		"HEALTH_CHECK_TIMEOUT":     c.HEALTH_CHECK_TIMEOUT,
+	} {
+		if d <= 0 {
+			add("%s must be positive, got %s", name, d)
+		}
+	}
+	if c.SHUTDOWN_DRAIN < 0 {
+		add("SHUTDOWN_DRAIN must not be negative, got %s", c.SHUTDOWN_DRAIN)
+	}
+	if c.HEALTH_CACHE_TTL < 0 {
+		add("HEALTH_CACHE_TTL must not be negative, got %s", c.HEALTH_CACHE_TTL)
+	}
+	if c.HTTP_MAX_HEADER_BYTES <= 0 {
+		add("HTTP_MAX_HEADER_BYTES must be positive, got %d", c.HTTP_MAX_HEADER_BYTES)
+	}
+	if c.COOKIE_AGE_HOURS <= 0 {
+		add("COOKIE_AGE_HOURS must be positive, got %d", c.COOKIE_AGE_HOURS)
+	}
+	if c.MAX_BODY_BYTES <= 0 {
+		add("MAX_BODY_BYTES must be positive, got %d", c.MAX_BODY_BYTES)
+	}
+	for name, value := range map[string]string{"API_URL": c.API_URL, "CLIENT_URL": c.CLIENT_URL} {
+		if u, err := url.Parse(value); value != "" && (err != nil || !u.IsAbs()) {
+			add("%s must be an absolute URL, got %q", name, value)
+		}
+	}
+
+	if c.GO_ENV == "production" {
+		if c.JWT_TOKEN != "" && len(c.JWT_TOKEN) < minSecretLength {
+			add("JWT_TOKEN must be at least %d characters in production", minSecretLength)
+		}
+		if c.CLIENT_URL == "" {
+			add("CLIENT_URL is required in production")
+		}
+		if c.DEBUG {
+			add("DEBUG must be off in production")
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// reports whether origin is *, or a scheme and host with an optional port and
+// leading *. wildcard label, without a path
+func validOrigin(origin string) bool {
+	if origin == "*" {
+		return true
+	}
+	u, err := url.Parse(strings.Replace(origin, "://*.", "://", 1))
+	return err == nil && u.Scheme != "" && u.Host != "" && strings.TrimSuffix(u.Path, "/") == "" &&
+		u.User == nil && u.RawQuery == "" && u.Fragment == "" && !strings.Contains(u.Host, "*")
+}
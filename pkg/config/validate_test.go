@@ -0,0 +1,49 @@
+package config
+
+import (
+	"flag"
+	"strings"
+	"testing"
+)
+
+func TestLoadAndValidate(t *testing.T) {
+	t.Setenv("JWT_TOKEN", "secret")
+	t.Setenv("DB_URL", "postgres://localhost/test")
+	t.Setenv("CORS_ALLOWED_ORIGINS", "https://*.example.com,https://app.example.com")
+
+	tests := []struct {
+		name string
+		args []string
+		want string // expected part of the error, "" when valid
+	}{
+		{"defaults", nil, ""},
+		{"flags override the environment", []string{"-port=9000", "-trusted-proxies=10.0.0.0/8,192.168.1.1"}, ""},
+		{"invalid trusted proxy", []string{"-trusted-proxies=10.0.0.0/8,proxy.local"}, `TRUSTED_PROXIES must contain IPs or CIDR ranges, got "proxy.local"`},
+		{"wildcard origin with credentials", []string{"-cors-allowed-origins=*", "-cors-allow-credentials=true"}, "must not contain * when CORS_ALLOW_CREDENTIALS is on"},
+		{"invalid origin", []string{"-cors-allowed-origins=example.com"}, "CORS_ALLOWED_ORIGINS must contain origins"},
+		{"invalid frame options", []string{"-security-frame-options=ALLOW"}, "SECURITY_FRAME_OPTIONS must be one of"},
+		{"TLS key without certificate", []string{"-tls-key-file=server.key"}, "TLS_CERT_FILE and TLS_KEY_FILE must be set together"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, err := Load(flag.NewFlagSet("test", flag.ContinueOnError), tt.args)
+			if err != nil {
+				t.Fatalf("Load: %v", err)
+			}
+			err = c.Validate()
+			switch {
+			case tt.want == "" && err != nil:
+				t.Errorf("unexpected error: %v", err)
+			case tt.want != "" && (err == nil || !strings.Contains(err.Error(), tt.want)):
+				t.Errorf("error %v, want one containing %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestLoadInvalidValue(t *testing.T) {
+	t.Setenv("PORT", "abc")
+	if _, err := Load(nil, nil); err == nil || !strings.Contains(err.Error(), "PORT") {
+		t.Errorf("error %v, want one naming PORT", err)
+	}
+}
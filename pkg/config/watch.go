@@ -0,0 +1,140 @@
+package config
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"reflect"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+// Change is a configuration value that differs after a reload. Secret values are
+// never included.
+type Change struct {
+	Key string
+	Old any
+	New any
+	// the value is not applied until the server is restarted
+	Restart bool
+}
+
+var (
+	subscribersMu sync.Mutex
+	subscribers   []func(old, new *Config)
+	// serializes reloads so subscribers see them in order
+	reloadMu sync.Mutex
+)
+
+// Subscribe registers fn to be called after each reload that changes the
+// configuration, with the previous and the new configuration
+func Subscribe(fn func(old, new *Config)) {
+	subscribersMu.Lock()
+	defer subscribersMu.Unlock()
+	subscribers = append(subscribers, fn)
+}
+
+// Reload builds the configuration again from the sources it was loaded from and,
+// when it is valid, swaps it in and notifies subscribers. Values that need a restart
+// keep their running value. It returns the changed non-secret values; an invalid
+// configuration is reported and leaves the running one in place.
+func Reload() ([]Change, error) {
+	reloadMu.Lock()
+	defer reloadMu.Unlock()
+
+	old := APP()
+	src := loadedFrom.Load()
+	if src == nil {
+		src = &sources{}
+	}
+	next, err := build(src)
+	if err != nil {
+		return nil, err
+	}
+	if err := next.Validate(); err != nil {
+		return nil, err
+	}
+
+	var changes []Change
+	oldFields := fields(&old)
+	for i, f := range fields(next) {
+		prev := oldFields[i]
+		if reflect.DeepEqual(prev.value.Interface(), f.value.Interface()) {
+			continue
+		}
+		if !f.secret {
+			changes = append(changes, Change{Key: f.key, Old: prev.value.Interface(), New: f.value.Interface(), Restart: f.restart})
+		}
+		if f.restart {
+			f.value.Set(prev.value)
+		}
+	}
+	if reflect.DeepEqual(old, *next) {
+		return changes, nil
+	}
+
+	current.Store(next)
+	subscribersMu.Lock()
+	notify := append([]func(old, new *Config){}, subscribers...)
+	subscribersMu.Unlock()
+	for _, fn := range notify {
+		fn(&old, next)
+	}
+	return changes, nil
+}
+
+// Watch reloads the configuration on SIGHUP and, when it was loaded from a config
+// file, whenever that file changes, until ctx is done. Each reload is passed to
+// report with its changes or error.
+func Watch(ctx context.Context, report func([]Change, error)) error {
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	defer signal.Stop(hup)
+
+	var fileEvents <-chan fsnotify.Event
+	var watchErrors <-chan error
+	var file string
+	if src := loadedFrom.Load(); src != nil && src.file != "" {
+		watcher, err := fsnotify.NewWatcher()
+		if err != nil {
+			return err
+		}
+		defer watcher.Close()
+		file, err = filepath.Abs(src.file)
+		if err != nil {
+			return err
+		}
+		// watch the directory, editors and config management replace the file
+		if err := watcher.Add(filepath.Dir(file)); err != nil {
+			return err
+		}
+		fileEvents, watchErrors = watcher.Events, watcher.Errors
+	}
+
+	// file changes usually come as several events, reload once they settle
+	debounce := time.NewTimer(time.Hour)
+	debounce.Stop()
+	defer debounce.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-hup:
+			report(Reload())
+		case event := <-fileEvents:
+			if filepath.Clean(event.Name) == file && event.Op&(fsnotify.Write|fsnotify.Create|fsnotify.Rename) != 0 {
+				debounce.Reset(100 * time.Millisecond)
+			}
+		case <-debounce.C:
+			report(Reload())
+		case err := <-watchErrors:
+			report(nil, fmt.Errorf("watching config file: %w", err))
+		}
+	}
+}
@@ -1,8 +1,15 @@
 package pkg
 
-import "golang.org/x/crypto/bcrypt"
+import (
+	"context"
+	"go-rest-template/pkg/tracing"
 
-func GenerateHash(password string) (string, error) {
+	"golang.org/x/crypto/bcrypt"
+)
+
+func GenerateHash(ctx context.Context, password string) (string, error) {
+	_, span := tracing.Start(ctx, "bcrypt.GenerateHash")
+	defer span.End()
 	hashed, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
 	if err != nil {
 		return "", err
@@ -10,6 +17,8 @@ func GenerateHash(password string) (string, error) {
 	return string(hashed), nil
 }
 
-func CompareHashAndPassword(hash string, password string) error {
+func CompareHashAndPassword(ctx context.Context, hash string, password string) error {
+	_, span := tracing.Start(ctx, "bcrypt.CompareHashAndPassword")
+	defer span.End()
 	return bcrypt.CompareHashAndPassword([]byte(hash), []byte(password))
 }
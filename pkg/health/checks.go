@@ -0,0 +1,123 @@
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"sync"
+	"time"
+
+	"go-rest-template/pkg/config"
+	"go-rest-template/pkg/logger"
+)
+
+// Check reports whether a dependency is usable, returning nil when it is
+type Check func(ctx context.Context) error
+
+type registeredCheck struct {
+	name    string
+	check   Check
+	timeout time.Duration
+}
+
+// CheckResult is the outcome of one check in the /readyz response
+type CheckResult struct {
+	Status    string  `json:"status"`
+	Error     string  `json:"error,omitempty"`
+	LatencyMS float64 `json:"latency_ms"`
+}
+
+// Report is the /readyz response
+type Report struct {
+	Status    string                 `json:"status"`
+	Checks    map[string]CheckResult `json:"checks,omitempty"`
+	CheckedAt time.Time              `json:"checked_at"`
+}
+
+const (
+	statusOK   = "ok"
+	statusFail = "fail"
+)
+
+var (
+	mu     sync.Mutex
+	checks []registeredCheck
+	// the last report is reused for HEALTH_CACHE_TTL so frequent probes don't hammer dependencies
+	cached *Report
+)
+
+// registers a readiness check, which fails when it takes longer than timeout
+func Register(name string, timeout time.Duration, check Check) {
+	mu.Lock()
+	defer mu.Unlock()
+	checks = append(checks, registeredCheck{name: name, check: check, timeout: timeout})
+	cached = nil
+}
+
+// runs the registered checks concurrently, or returns the cached report when it is recent
+func Run(ctx context.Context) Report {
+	mu.Lock()
+	defer mu.Unlock()
+	if cached != nil && time.Since(cached.CheckedAt) < config.APP().HEALTH_CACHE_TTL {
+		return *cached
+	}
+	// the result is shared with other callers, don't let this request's cancellation fail it
+	ctx = context.WithoutCancel(ctx)
+
+	report := Report{Status: statusOK, Checks: make(map[string]CheckResult, len(checks)), CheckedAt: time.Now()}
+	results := make([]CheckResult, len(checks))
+	var wg sync.WaitGroup
+	for i, c := range checks {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			ctx, cancel := context.WithTimeout(ctx, c.timeout)
+			defer cancel()
+			start := time.Now()
+			err := c.check(ctx)
+			results[i] = CheckResult{Status: statusOK, LatencyMS: float64(time.Since(start).Microseconds()) / 1000}
+			if err != nil {
+				results[i].Status = statusFail
+				results[i].Error = err.Error()
+			}
+		}()
+	}
+	wg.Wait()
+
+	for i, c := range checks {
+		report.Checks[c.name] = results[i]
+		if results[i].Status != statusOK {
+			report.Status = statusFail
+			logger.FromContext(ctx).Error("readiness check failed", logger.Fields{"check": c.name, "error": results[i].Error})
+		}
+	}
+	cached = &report
+	return report
+}
+
+// answers 200 while the process is running
+func Liveness(w http.ResponseWriter, r *http.Request) {
+	writeReport(w, http.StatusOK, Report{Status: statusOK, CheckedAt: time.Now()})
+}
+
+// answers 200 when the server is ready and every check passes, 503 otherwise,
+// with the result of each check
+func Readiness(w http.ResponseWriter, r *http.Request) {
+	if !Ready() {
+		writeReport(w, http.StatusServiceUnavailable, Report{Status: "not_ready", CheckedAt: time.Now()})
+		return
+	}
+	report := Run(r.Context())
+	status := http.StatusOK
+	if report.Status != statusOK {
+		status = http.StatusServiceUnavailable
+	}
+	writeReport(w, status, report)
+}
+
+func writeReport(w http.ResponseWriter, status int, report Report) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(report)
+}
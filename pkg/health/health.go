@@ -0,0 +1,41 @@
+package health
+
+import (
+	"net/http"
+	"strings"
+	"sync/atomic"
+)
+
+var ready atomic.Bool
+
+// marks the server as ready to receive traffic, or not ready while starting and
+// draining before shutdown
+func SetReady(r bool) {
+	ready.Store(r)
+}
+
+// reports whether the server is ready to receive traffic
+func Ready() bool {
+	return ready.Load()
+}
+
+// Middleware that answers GET and HEAD requests to path with 200 while the server
+// is ready and 503 otherwise, so load balancers stop routing to it during shutdown
+func Heartbeat(path string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if (r.Method == http.MethodGet || r.Method == http.MethodHead) && strings.EqualFold(r.URL.Path, path) {
+				w.Header().Set("Content-Type", "text/plain")
+				if !Ready() {
+					w.WriteHeader(http.StatusServiceUnavailable)
+					w.Write([]byte("not ready"))
+					return
+				}
+				w.WriteHeader(http.StatusOK)
+				w.Write([]byte("."))
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
@@ -0,0 +1,100 @@
+package logger
+
+import (
+	"context"
+	"sync"
+
+	"github.com/rs/zerolog"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Fields are key/value pairs added to structured log entries
+type Fields map[string]any
+
+// Logger writes entries carrying a fixed set of fields. Request loggers are
+// shared by the middleware chain so fields added later, like the user ID,
+// also appear in the access log.
+type Logger struct {
+	mu sync.RWMutex
+	zl zerolog.Logger
+}
+
+type contextKey struct{}
+
+// returns a logger adding fields to every entry
+func With(fields Fields) *Logger {
+	Initialize()
+	return &Logger{zl: logger.With().Fields(map[string]any(fields)).Logger()}
+}
+
+// returns a child logger adding fields to every entry
+func (l *Logger) With(fields Fields) *Logger {
+	return &Logger{zl: l.zerolog().With().Fields(map[string]any(fields)).Logger()}
+}
+
+// returns a copy of ctx carrying l
+func WithContext(ctx context.Context, l *Logger) context.Context {
+	return context.WithValue(ctx, contextKey{}, l)
+}
+
+// returns the logger of ctx, the request logger inside HTTP handlers, or a
+// logger without fields when there is none
+func FromContext(ctx context.Context) *Logger {
+	if l, ok := ctx.Value(contextKey{}).(*Logger); ok {
+		return l
+	}
+	fields := Fields{}
+	addTraceFields(ctx, fields)
+	return With(fields)
+}
+
+// adds the trace and span IDs of the span in ctx, if any
+func addTraceFields(ctx context.Context, fields Fields) {
+	if sc := trace.SpanContextFromContext(ctx); sc.IsValid() {
+		fields["trace_id"] = sc.TraceID().String()
+		fields["span_id"] = sc.SpanID().String()
+	}
+}
+
+// adds fields to the logger of ctx, for values only known further down the
+// middleware chain
+func AddFields(ctx context.Context, fields Fields) {
+	l, ok := ctx.Value(contextKey{}).(*Logger)
+	if !ok {
+		return
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.zl = l.zl.With().Fields(map[string]any(fields)).Logger()
+}
+
+func (l *Logger) zerolog() zerolog.Logger {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	return l.zl
+}
+
+func (l *Logger) log(level zerolog.Level, msg string, fields []Fields) {
+	zl := l.zerolog()
+	e := zl.WithLevel(level).CallerSkipFrame(2)
+	for _, f := range fields {
+		e = e.Fields(map[string]any(f))
+	}
+	e.Msg(msg)
+}
+
+func (l *Logger) Debug(msg string, fields ...Fields) {
+	l.log(zerolog.DebugLevel, msg, fields)
+}
+
+func (l *Logger) Info(msg string, fields ...Fields) {
+	l.log(zerolog.InfoLevel, msg, fields)
+}
+
+func (l *Logger) Warn(msg string, fields ...Fields) {
+	l.log(zerolog.WarnLevel, msg, fields)
+}
+
+func (l *Logger) Error(msg string, fields ...Fields) {
+	l.log(zerolog.ErrorLevel, msg, fields)
+}
@@ -3,7 +3,8 @@ package logger
 import (
 	"fmt"
 	"go-rest-template/pkg/config"
-	"os"
+	"io"
+	"strings"
 	"sync"
 
 	"github.com/rs/zerolog"
@@ -11,6 +12,7 @@ import (
 
 var logger *zerolog.Logger
 var once sync.Once
+var outputs []*sink
 
 func Initialize() {
 	once.Do(func() {
@@ -35,45 +37,87 @@ func setupLogger() {
 		return fmt.Sprintf("%s:%d", short, line)
 	}
 
-	var l zerolog.Logger
+	console := env == "development" || debug
+	sinks, err := openSinks(config.APP().LOG_OUTPUTS, console)
+	var openErr error
+	if err != nil || len(sinks) == 0 {
+		// fall back to stdout so the error itself can be logged
+		openErr = err
+		sinks, _ = openSinks([]string{"stdout"}, console)
+	}
+	outputs = sinks
+	writers := make([]io.Writer, len(sinks))
+	for i, s := range sinks {
+		writers[i] = s
+	}
 
-	if env == "development" || debug {
+	ctx := zerolog.New(redactWriter{next: zerolog.MultiLevelWriter(writers...)}).With().Timestamp()
+	if console {
 		// Development or debug
-		l = zerolog.New(zerolog.ConsoleWriter{
-			Out:        os.Stdout,
-			TimeFormat: "15:04:05",
-		}).With().Timestamp().CallerWithSkipFrameCount(2).Logger()
-
-		l = l.Level(zerolog.DebugLevel)
-	} else {
-		// Production
-		l = zerolog.New(os.Stdout).With().Timestamp().Logger()
-		l = l.Level(zerolog.InfoLevel)
+		ctx = ctx.CallerWithSkipFrameCount(2)
+	}
+	l := ctx.Logger()
+	if n := config.APP().LOG_SAMPLE_DEBUG; n > 1 {
+		l = l.Sample(&zerolog.LevelSampler{DebugSampler: &zerolog.BasicSampler{N: uint32(n)}})
+	}
+	if extra := config.APP().LOG_REDACT_KEYS; len(extra) > 0 {
+		RedactKeys(extra...)
 	}
 
+	// the level is global so it can follow configuration reloads
+	cfg := config.APP()
+	zerolog.SetGlobalLevel(level(&cfg))
+	config.Subscribe(func(old, new *config.Config) {
+		if level(old) != level(new) {
+			zerolog.SetGlobalLevel(level(new))
+			InfoF("Log level set to %s", level(new))
+		}
+	})
+
 	logger = &l
+	if openErr != nil {
+		ErrorF("Failed to open log outputs, writing to stdout: %v", openErr)
+	}
 }
 
-// Simple logging functions
+// flushes and closes file and network log outputs
+func Close() error {
+	return closeSinks(outputs)
+}
+
+// returns LOG_LEVEL, or debug in development and debug mode and info otherwise
+func level(c *config.Config) zerolog.Level {
+	if c.LOG_LEVEL != "" {
+		if l, err := zerolog.ParseLevel(c.LOG_LEVEL); err == nil {
+			return l
+		}
+	}
+	if c.GO_ENV == "development" || c.DEBUG {
+		return zerolog.DebugLevel
+	}
+	return zerolog.InfoLevel
+}
+
+// Simple logging functions, the values are printed separated by spaces
 
 func Debug(v ...interface{}) {
 	Initialize()
-	logger.Debug().CallerSkipFrame(1).Msgf("%v", v)
+	logger.Debug().CallerSkipFrame(1).Msg(sprint(v))
 }
 
 func Info(v ...interface{}) {
 	Initialize()
-	logger.Info().CallerSkipFrame(1).Msgf("%v", v)
+	logger.Info().CallerSkipFrame(1).Msg(sprint(v))
 }
 
 func Error(v ...interface{}) {
 	Initialize()
-	logger.Error().CallerSkipFrame(1).Msgf("%v", v)
+	logger.Error().CallerSkipFrame(1).Msg(sprint(v))
 }
 
 func Panic(v ...interface{}) {
 	Initialize()
-	logger.Panic().CallerSkipFrame(1).Msgf("%v", v)
+	logger.Panic().CallerSkipFrame(1).Msg(sprint(v))
 }
 
 // Formatted logging functions
@@ -97,3 +141,7 @@ func PanicF(format string, v ...interface{}) {
 	Initialize()
 	logger.Panic().CallerSkipFrame(1).Msgf(format, v...)
 }
+
+func sprint(v []interface{}) string {
+	return strings.TrimSuffix(fmt.Sprintln(v...), "\n")
+}
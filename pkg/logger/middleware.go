@@ -0,0 +1,64 @@
+package logger
+
+import (
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	chiMiddleware "github.com/go-chi/chi/v5/middleware"
+	"github.com/rs/zerolog"
+)
+
+// Middleware that adds a request logger with the request ID, method, path and
+// remote IP to the context and writes an access log entry with the status, size
+// and latency once the request is done. It goes after middleware.RequestID and
+// security.RealIP.
+func RequestLogger(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		fields := Fields{
+			"request_id": chiMiddleware.GetReqID(r.Context()),
+			"method":     r.Method,
+			"path":       r.URL.Path,
+			"remote_ip":  RemoteIP(r),
+		}
+		addTraceFields(r.Context(), fields)
+		l := With(fields)
+		ww := chiMiddleware.NewWrapResponseWriter(w, r.ProtoMajor)
+
+		defer func() {
+			status := ww.Status()
+			if status == 0 {
+				status = http.StatusOK
+			}
+			level := zerolog.InfoLevel
+			switch {
+			case status >= http.StatusInternalServerError:
+				level = zerolog.ErrorLevel
+			case status >= http.StatusBadRequest:
+				level = zerolog.WarnLevel
+			}
+			fields := Fields{
+				"status":     status,
+				"bytes":      ww.BytesWritten(),
+				"latency_ms": float64(time.Since(start).Microseconds()) / 1000,
+			}
+			if rctx := chi.RouteContext(r.Context()); rctx != nil && rctx.RoutePattern() != "" {
+				fields["route"] = rctx.RoutePattern()
+			}
+			l.log(level, "request", []Fields{fields})
+		}()
+
+		next.ServeHTTP(ww, r.WithContext(WithContext(r.Context(), l)))
+	})
+}
+
+// RemoteIP returns the client IP of a request without the port. Mounted after
+// security.RealIP, it is the address trusted proxies forwarded.
+func RemoteIP(r *http.Request) string {
+	if host, _, err := net.SplitHostPort(r.RemoteAddr); err == nil {
+		return host
+	}
+	return r.RemoteAddr
+}
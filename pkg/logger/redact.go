@@ -0,0 +1,250 @@
+package logger
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/rs/zerolog"
+)
+
+const redacted = "[REDACTED]"
+
+// field names masked in every entry, compared case-insensitively
+var (
+	redactMu   sync.RWMutex
+	redactKeys = map[string]bool{
+		"password":      true,
+		"password_hash": true,
+		"access_token":  true,
+		"refresh_token": true,
+		"authorization": true,
+		"cookie":        true,
+		"jwt_token":     true,
+	}
+)
+
+// header fields whose value is "<scheme> <credentials>", masked keeping the scheme
+var credentialKeys = map[string]bool{
+	"authorization":       true,
+	"proxy-authorization": true,
+}
+
+// credentials recognised in free text, e.g. a header copied into a message. Only
+// credential shapes match: a JWT, a Bearer token of 20+ characters or Basic
+// credentials decoding to user:password, so prose like "basic validation" is kept.
+const jwtPattern = `eyJ[A-Za-z0-9_-]+\.[A-Za-z0-9_-]+\.[A-Za-z0-9_-]*`
+
+var (
+	jwtRegex    = regexp.MustCompile(`\b` + jwtPattern)
+	bearerRegex = regexp.MustCompile(`(?i)\b(bearer)\s+(?:` + jwtPattern + `|[A-Za-z0-9\-._~+/]{20,}=*)`)
+	basicRegex  = regexp.MustCompile(`(?i)\b(basic)\s+([A-Za-z0-9+/]{16,}={0,2})`)
+	schemeRegex = regexp.MustCompile(`^\s*([A-Za-z][A-Za-z0-9\-]*)\s+\S`)
+)
+
+// adds field names to mask in log entries, at any depth
+func RedactKeys(keys ...string) {
+	redactMu.Lock()
+	defer redactMu.Unlock()
+	for _, k := range keys {
+		redactKeys[strings.ToLower(k)] = true
+	}
+}
+
+// redactWriter masks the values of redacted fields before an entry reaches the sinks
+type redactWriter struct {
+	next zerolog.LevelWriter
+}
+
+func (w redactWriter) Write(p []byte) (int, error) {
+	return w.WriteLevel(zerolog.NoLevel, p)
+}
+
+func (w redactWriter) WriteLevel(level zerolog.Level, p []byte) (int, error) {
+	if out, ok := redact(p); ok {
+		if _, err := w.next.WriteLevel(level, out); err != nil {
+			return 0, err
+		}
+		return len(p), nil
+	}
+	return w.next.WriteLevel(level, p)
+}
+
+// returns the entry with redacted fields and credentials masked, or false when
+// nothing was masked. Fields keep their order.
+func redact(p []byte) ([]byte, bool) {
+	redactMu.RLock()
+	defer redactMu.RUnlock()
+
+	// most entries have none of the keys or credentials, skip decoding them
+	lower := bytes.ToLower(p)
+	found := bytes.Contains(lower, []byte("bearer")) || bytes.Contains(lower, []byte("basic")) ||
+		bytes.Contains(p, []byte("eyJ")) || bytes.Contains(lower, []byte(`"proxy-authorization"`))
+	for k := range redactKeys {
+		if found {
+			break
+		}
+		found = bytes.Contains(lower, []byte(`"`+k+`"`))
+	}
+	if !found {
+		return nil, false
+	}
+
+	dec := json.NewDecoder(bytes.NewReader(p))
+	dec.UseNumber()
+	r := &redactor{dec: dec}
+	if err := r.copyValue(maskNone); err != nil || !r.changed {
+		return nil, false
+	}
+	return append(r.out.Bytes(), '\n'), true
+}
+
+type maskMode int
+
+const (
+	maskNone maskMode = iota
+	// replace the whole value
+	maskAll
+	// replace the credentials of a "<scheme> <credentials>" value
+	maskCredential
+)
+
+// redactor copies a JSON value token by token, masking as it goes
+type redactor struct {
+	dec     *json.Decoder
+	out     bytes.Buffer
+	changed bool
+}
+
+func keyMode(key string) maskMode {
+	key = strings.ToLower(key)
+	switch {
+	case credentialKeys[key]:
+		return maskCredential
+	case redactKeys[key]:
+		return maskAll
+	default:
+		return maskNone
+	}
+}
+
+func (r *redactor) copyValue(mode maskMode) error {
+	tok, err := r.dec.Token()
+	if err != nil {
+		return err
+	}
+	delim, isDelim := tok.(json.Delim)
+	if mode == maskAll || (mode == maskCredential && isDelim && delim == '{') {
+		r.changed = true
+		r.writeString(redacted)
+		if isDelim {
+			return r.skip()
+		}
+		return nil
+	}
+
+	switch t := tok.(type) {
+	case json.Delim:
+		if t == '{' {
+			r.out.WriteByte('{')
+			for i := 0; r.dec.More(); i++ {
+				if i > 0 {
+					r.out.WriteByte(',')
+				}
+				key, err := r.dec.Token()
+				if err != nil {
+					return err
+				}
+				r.writeString(key.(string))
+				r.out.WriteByte(':')
+				if err := r.copyValue(keyMode(key.(string))); err != nil {
+					return err
+				}
+			}
+			r.out.WriteByte('}')
+		} else {
+			// header values are logged as lists, their items keep the mode
+			r.out.WriteByte('[')
+			for i := 0; r.dec.More(); i++ {
+				if i > 0 {
+					r.out.WriteByte(',')
+				}
+				if err := r.copyValue(mode); err != nil {
+					return err
+				}
+			}
+			r.out.WriteByte(']')
+		}
+		_, err := r.dec.Token() // closing delimiter
+		return err
+	case string:
+		masked := redactText(t)
+		if mode == maskCredential {
+			masked = maskScheme(t)
+		}
+		if masked != t {
+			r.changed = true
+		}
+		r.writeString(masked)
+	case json.Number:
+		r.out.WriteString(t.String())
+	case bool:
+		r.out.WriteString(strconv.FormatBool(t))
+	case nil:
+		r.out.WriteString("null")
+	}
+	return nil
+}
+
+// skips the rest of an object or array whose opening delimiter was read
+func (r *redactor) skip() error {
+	for depth := 1; depth > 0; {
+		tok, err := r.dec.Token()
+		if err != nil {
+			return err
+		}
+		if d, ok := tok.(json.Delim); ok {
+			if d == '{' || d == '[' {
+				depth++
+			} else {
+				depth--
+			}
+		}
+	}
+	return nil
+}
+
+func (r *redactor) writeString(s string) {
+	enc := json.NewEncoder(&r.out)
+	// zerolog doesn't escape HTML characters either
+	enc.SetEscapeHTML(false)
+	enc.Encode(s)
+	// Encode ends the value with a newline
+	r.out.Truncate(r.out.Len() - 1)
+}
+
+// masks a "<scheme> <credentials>" value keeping the scheme, or all of it
+func maskScheme(s string) string {
+	if m := schemeRegex.FindStringSubmatch(s); m != nil {
+		return m[1] + " " + redacted
+	}
+	return redacted
+}
+
+// masks the credentials found in free text
+func redactText(s string) string {
+	s = bearerRegex.ReplaceAllString(s, "$1 "+redacted)
+	s = basicRegex.ReplaceAllStringFunc(s, func(match string) string {
+		m := basicRegex.FindStringSubmatch(match)
+		decoded, err := base64.StdEncoding.DecodeString(m[2])
+		if err != nil || !bytes.ContainsRune(decoded, ':') {
+			return match
+		}
+		return m[1] + " " + redacted
+	})
+	return jwtRegex.ReplaceAllString(s, redacted)
+}
@@ -0,0 +1,248 @@
+package logger
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"math"
+	"net"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog"
+	"gopkg.in/natefinch/lumberjack.v2"
+)
+
+// sink is an output with its own minimum level
+type sink struct {
+	w      io.Writer
+	min    zerolog.Level
+	closer io.Closer
+}
+
+func (s *sink) Write(p []byte) (int, error) {
+	return s.w.Write(p)
+}
+
+func (s *sink) WriteLevel(level zerolog.Level, p []byte) (int, error) {
+	if level < s.min {
+		return len(p), nil
+	}
+	if lw, ok := s.w.(zerolog.LevelWriter); ok {
+		return lw.WriteLevel(level, p)
+	}
+	return s.w.Write(p)
+}
+
+// opens the sinks of LOG_OUTPUTS. Each output is a URI with an optional level parameter:
+//
+//	stdout, stderr                      ?format=console|json, console by default in development
+//	file://logs/app.log                 ?max_size=100 (MB) &max_backups=5 &max_age=168h &rotate=24h &compress=true
+//	udp://localhost:514                 ?facility=1 &tag=app, syslog (RFC 5424) over UDP
+func openSinks(outputs []string, console bool) ([]*sink, error) {
+	var sinks []*sink
+	for _, output := range outputs {
+		s, err := openSink(output, console)
+		if err != nil {
+			closeSinks(sinks)
+			return nil, fmt.Errorf("log output %q: %w", output, err)
+		}
+		sinks = append(sinks, s)
+	}
+	return sinks, nil
+}
+
+func openSink(output string, console bool) (*sink, error) {
+	u, err := url.Parse(output)
+	if err != nil {
+		return nil, err
+	}
+	query := u.Query()
+	s := &sink{min: zerolog.TraceLevel}
+	if level := query.Get("level"); level != "" {
+		if s.min, err = zerolog.ParseLevel(level); err != nil {
+			return nil, err
+		}
+	}
+
+	target := u.Scheme
+	if target == "" {
+		target = u.Path
+	}
+	switch target {
+	case "stdout", "stderr":
+		out := os.Stdout
+		if target == "stderr" {
+			out = os.Stderr
+		}
+		switch query.Get("format") {
+		case "console":
+			console = true
+		case "json":
+			console = false
+		}
+		s.w = out
+		if console {
+			s.w = zerolog.ConsoleWriter{Out: out, TimeFormat: "15:04:05"}
+		}
+	case "file":
+		path := filepath.FromSlash(u.Host + u.Path)
+		if path == "" {
+			return nil, fmt.Errorf("missing file path")
+		}
+		file := &lumberjack.Logger{Filename: path, MaxSize: 100, LocalTime: true}
+		if err := intParam(query, "max_size", &file.MaxSize); err != nil {
+			return nil, err
+		}
+		if err := intParam(query, "max_backups", &file.MaxBackups); err != nil {
+			return nil, err
+		}
+		if v := query.Get("max_age"); v != "" {
+			age, err := time.ParseDuration(v)
+			if err != nil {
+				return nil, fmt.Errorf("max_age: %w", err)
+			}
+			file.MaxAge = int(math.Ceil(age.Hours() / 24))
+		}
+		file.Compress = query.Get("compress") == "true"
+		s.w, s.closer = file, file
+		if v := query.Get("rotate"); v != "" {
+			every, err := time.ParseDuration(v)
+			if err != nil || every <= 0 {
+				return nil, fmt.Errorf("rotate must be a positive duration like 24h")
+			}
+			s.closer = rotateEvery(file, every)
+		}
+	case "udp":
+		w, err := dialSyslog(u.Host, query)
+		if err != nil {
+			return nil, err
+		}
+		s.w, s.closer = w, w
+	default:
+		return nil, fmt.Errorf("unsupported output %q, use stdout, stderr, file or udp", target)
+	}
+	return s, nil
+}
+
+func intParam(query url.Values, name string, target *int) error {
+	v := query.Get(name)
+	if v == "" {
+		return nil
+	}
+	n, err := strconv.Atoi(v)
+	if err != nil || n < 0 {
+		return fmt.Errorf("%s must be a non-negative integer", name)
+	}
+	*target = n
+	return nil
+}
+
+// rotates the file on a fixed interval in addition to its size limit
+func rotateEvery(file *lumberjack.Logger, every time.Duration) io.Closer {
+	ticker := time.NewTicker(every)
+	done := make(chan struct{})
+	go func() {
+		for {
+			select {
+			case <-ticker.C:
+				file.Rotate()
+			case <-done:
+				return
+			}
+		}
+	}()
+	return closerFunc(func() error {
+		ticker.Stop()
+		close(done)
+		return file.Close()
+	})
+}
+
+type closerFunc func() error
+
+func (f closerFunc) Close() error {
+	return f()
+}
+
+func closeSinks(sinks []*sink) error {
+	var firstErr error
+	for _, s := range sinks {
+		if s.closer == nil {
+			continue
+		}
+		if err := s.closer.Close(); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
+
+// syslogWriter sends each entry as an RFC 5424 message in a UDP datagram
+type syslogWriter struct {
+	mu       sync.Mutex
+	conn     net.Conn
+	facility int
+	hostname string
+	tag      string
+}
+
+func dialSyslog(addr string, query url.Values) (*syslogWriter, error) {
+	w := &syslogWriter{facility: 1, tag: query.Get("tag")}
+	if err := intParam(query, "facility", &w.facility); err != nil {
+		return nil, err
+	}
+	if w.facility > 23 {
+		return nil, fmt.Errorf("facility must be between 0 and 23")
+	}
+	if w.tag == "" {
+		w.tag = filepath.Base(os.Args[0])
+	}
+	w.hostname, _ = os.Hostname()
+	if w.hostname == "" {
+		w.hostname = "-"
+	}
+	conn, err := net.Dial("udp", addr)
+	if err != nil {
+		return nil, err
+	}
+	w.conn = conn
+	return w, nil
+}
+
+func (w *syslogWriter) Write(p []byte) (int, error) {
+	return w.WriteLevel(zerolog.NoLevel, p)
+}
+
+func (w *syslogWriter) WriteLevel(level zerolog.Level, p []byte) (int, error) {
+	severity := 6 // informational
+	switch level {
+	case zerolog.TraceLevel, zerolog.DebugLevel:
+		severity = 7
+	case zerolog.WarnLevel:
+		severity = 4
+	case zerolog.ErrorLevel:
+		severity = 3
+	case zerolog.FatalLevel:
+		severity = 2
+	case zerolog.PanicLevel:
+		severity = 0
+	}
+	msg := fmt.Sprintf("<%d>1 %s %s %s %d - - %s", w.facility*8+severity,
+		time.Now().Format(time.RFC3339Nano), w.hostname, w.tag, os.Getpid(), bytes.TrimRight(p, "\n"))
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if _, err := w.conn.Write([]byte(msg)); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+func (w *syslogWriter) Close() error {
+	return w.conn.Close()
+}
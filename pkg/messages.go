@@ -0,0 +1,301 @@
+package pkg
+
+import (
+	"net/http"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/go-playground/locales"
+	"github.com/go-playground/locales/en"
+	"github.com/go-playground/locales/es"
+	"github.com/go-playground/locales/fr"
+	ut "github.com/go-playground/universal-translator"
+	"github.com/go-playground/validator/v10"
+)
+
+// validation message templates per locale and tag, {0} is the tag parameter.
+// Length tags have variants for numbers (`_number`) and collections (`_items`).
+var validationMessages = map[string]map[string]string{
+	"en": {
+		"required":        "Missing required field",
+		"required_if":     "Missing required field",
+		"required_with":   "Missing required field",
+		"required_unless": "Missing required field",
+		"email":           "Invalid email format",
+		"min":             "Must be at least {0} characters",
+		"min_number":      "Must be at least {0}",
+		"min_items":       "Must contain at least {0} items",
+		"max":             "Must be at most {0} characters",
+		"max_number":      "Must be at most {0}",
+		"max_items":       "Must contain at most {0} items",
+		"len":             "Must be exactly {0} characters long",
+		"len_number":      "Must be exactly {0}",
+		"len_items":       "Must contain exactly {0} items",
+		"gte":             "Must be greater than or equal to {0}",
+		"lte":             "Must be less than or equal to {0}",
+		"gt":              "Must be greater than {0}",
+		"lt":              "Must be less than {0}",
+		"eqfield":         "Must match {0}",
+		"nefield":         "Must be different from {0}",
+		"gtfield":         "Must be greater than {0}",
+		"gtefield":        "Must be greater than or equal to {0}",
+		"ltfield":         "Must be less than {0}",
+		"ltefield":        "Must be less than or equal to {0}",
+		"number":          "Must be a valid number",
+		"numeric":         "Must contain only numeric characters",
+		"oneof":           "Must be one of: {0}",
+		"url":             "Must be a valid URL",
+		"uuid":            "Must be a valid UUID",
+		"alphanum":        "Must contain only alphanumeric characters",
+		"alpha":           "Must contain only alphabetic characters",
+		"boolean":         "Must be a valid boolean value",
+		"e164":            "Must be a phone number in E.164 format, e.g. +14155552671",
+		"slug":            "Must contain only lowercase letters, numbers and single hyphens",
+		"strong_password": "Must be at least 8 characters with upper and lower case letters, a number and a symbol",
+		"datetime":        "Must be a valid RFC 3339 date-time",
+		"type_string":     "Must be a string",
+		"type_integer":    "Must be an integer",
+		"type_object":     "Must be an object",
+		"type_array":      "Must be an array",
+		"type_null":       "Must be null",
+		"not_null":        "Must not be null",
+		"one_schema":      "Matches more than one schema",
+		"unknown_field":   "Unknown field",
+		"invalid":         "Invalid value",
+	},
+	"es": {
+		"required":        "Campo obligatorio",
+		"required_if":     "Campo obligatorio",
+		"required_with":   "Campo obligatorio",
+		"required_unless": "Campo obligatorio",
+		"email":           "Formato de correo electrónico no válido",
+		"min":             "Debe tener al menos {0} caracteres",
+		"min_number":      "Debe ser como mínimo {0}",
+		"min_items":       "Debe contener al menos {0} elementos",
+		"max":             "Debe tener como máximo {0} caracteres",
+		"max_number":      "Debe ser como máximo {0}",
+		"max_items":       "Debe contener como máximo {0} elementos",
+		"len":             "Debe tener exactamente {0} caracteres",
+		"len_number":      "Debe ser exactamente {0}",
+		"len_items":       "Debe contener exactamente {0} elementos",
+		"gte":             "Debe ser mayor o igual que {0}",
+		"lte":             "Debe ser menor o igual que {0}",
+		"gt":              "Debe ser mayor que {0}",
+		"lt":              "Debe ser menor que {0}",
+		"eqfield":         "Debe coincidir con {0}",
+		"nefield":         "Debe ser distinto de {0}",
+		"gtfield":         "Debe ser mayor que {0}",
+		"gtefield":        "Debe ser mayor o igual que {0}",
+		"ltfield":         "Debe ser menor que {0}",
+		"ltefield":        "Debe ser menor o igual que {0}",
+		"number":          "Debe ser un número válido",
+		"numeric":         "Debe contener solo caracteres numéricos",
+		"oneof":           "Debe ser uno de: {0}",
+		"url":             "Debe ser una URL válida",
+		"uuid":            "Debe ser un UUID válido",
+		"alphanum":        "Debe contener solo caracteres alfanuméricos",
+		"alpha":           "Debe contener solo letras",
+		"boolean":         "Debe ser un valor booleano válido",
+		"e164":            "Debe ser un número de teléfono en formato E.164, p. ej. +14155552671",
+		"slug":            "Debe contener solo letras minúsculas, números y guiones simples",
+		"strong_password": "Debe tener al menos 8 caracteres con mayúsculas, minúsculas, un número y un símbolo",
+		"datetime":        "Debe ser una fecha y hora RFC 3339 válida",
+		"type_string":     "Debe ser una cadena",
+		"type_integer":    "Debe ser un número entero",
+		"type_object":     "Debe ser un objeto",
+		"type_array":      "Debe ser una lista",
+		"type_null":       "Debe ser nulo",
+		"not_null":        "No puede ser nulo",
+		"one_schema":      "Coincide con más de un esquema",
+		"unknown_field":   "Campo desconocido",
+		"invalid":         "Valor no válido",
+	},
+	"fr": {
+		"required":        "Champ obligatoire",
+		"required_if":     "Champ obligatoire",
+		"required_with":   "Champ obligatoire",
+		"required_unless": "Champ obligatoire",
+		"email":           "Format d'e-mail invalide",
+		"min":             "Doit contenir au moins {0} caractères",
+		"min_number":      "Doit être au moins {0}",
+		"min_items":       "Doit contenir au moins {0} éléments",
+		"max":             "Doit contenir au plus {0} caractères",
+		"max_number":      "Doit être au plus {0}",
+		"max_items":       "Doit contenir au plus {0} éléments",
+		"len":             "Doit contenir exactement {0} caractères",
+		"len_number":      "Doit être exactement {0}",
+		"len_items":       "Doit contenir exactement {0} éléments",
+		"gte":             "Doit être supérieur ou égal à {0}",
+		"lte":             "Doit être inférieur ou égal à {0}",
+		"gt":              "Doit être supérieur à {0}",
+		"lt":              "Doit être inférieur à {0}",
+		"eqfield":         "Doit correspondre à {0}",
+		"nefield":         "Doit être différent de {0}",
+		"gtfield":         "Doit être supérieur à {0}",
+		"gtefield":        "Doit être supérieur ou égal à {0}",
+		"ltfield":         "Doit être inférieur à {0}",
+		"ltefield":        "Doit être inférieur ou égal à {0}",
+		"number":          "Doit être un nombre valide",
+		"numeric":         "Doit contenir uniquement des chiffres",
+		"oneof":           "Doit être l'un de : {0}",
+		"url":             "Doit être une URL valide",
+		"uuid":            "Doit être un UUID valide",
+		"alphanum":        "Doit contenir uniquement des caractères alphanumériques",
+		"alpha":           "Doit contenir uniquement des lettres",
+		"boolean":         "Doit être une valeur booléenne valide",
+		"e164":            "Doit être un numéro de téléphone au format E.164, p. ex. +14155552671",
+		"slug":            "Doit contenir uniquement des minuscules, des chiffres et des tirets simples",
+		"strong_password": "Doit contenir au moins 8 caractères avec majuscules, minuscules, un chiffre et un symbole",
+		"datetime":        "Doit être une date-heure RFC 3339 valide",
+		"type_string":     "Doit être une chaîne",
+		"type_integer":    "Doit être un entier",
+		"type_object":     "Doit être un objet",
+		"type_array":      "Doit être une liste",
+		"type_null":       "Doit être nul",
+		"not_null":        "Ne doit pas être nul",
+		"one_schema":      "Correspond à plus d'un schéma",
+		"unknown_field":   "Champ inconnu",
+		"invalid":         "Valeur invalide",
+	},
+}
+
+const defaultLocale = "en"
+
+var placeholderRegex = regexp.MustCompile(`\{(\d+)\}`)
+
+var (
+	placeholdersMu sync.RWMutex
+	// highest placeholder count of each key across locales
+	placeholders = map[string]int{}
+)
+
+// records how many params the template needs, T panics when given fewer
+func countPlaceholders(key, text string) {
+	n := 0
+	for _, m := range placeholderRegex.FindAllStringSubmatch(text, -1) {
+		if i, _ := strconv.Atoi(m[1]); i+1 > n {
+			n = i + 1
+		}
+	}
+	placeholdersMu.Lock()
+	defer placeholdersMu.Unlock()
+	placeholders[key] = max(placeholders[key], n)
+}
+
+// translates key, padding params with empty strings up to the template's placeholders
+func translate(trans ut.Translator, key string, params ...string) (string, error) {
+	placeholdersMu.RLock()
+	n := placeholders[key]
+	placeholdersMu.RUnlock()
+	for len(params) < n {
+		params = append(params, "")
+	}
+	return trans.T(key, params...)
+}
+
+var translators = newTranslators()
+
+func newTranslators() *ut.UniversalTranslator {
+	supported := []locales.Translator{en.New(), es.New(), fr.New()}
+	uni := ut.New(supported[0], supported...)
+	for locale, messages := range validationMessages {
+		trans, _ := uni.GetTranslator(locale)
+		for key, text := range messages {
+			if err := trans.Add(key, text, true); err != nil {
+				panic(err)
+			}
+			countPlaceholders(key, text)
+		}
+	}
+	return uni
+}
+
+// AddValidationMessage adds or replaces the message template of a tag for a locale.
+func AddValidationMessage(locale, tag, text string) error {
+	trans, found := translators.GetTranslator(locale)
+	if !found {
+		return ut.ErrUnknowTranslation
+	}
+	if err := trans.Add(tag, text, true); err != nil {
+		return err
+	}
+	countPlaceholders(tag, text)
+	return nil
+}
+
+// Translator returns the translator for a locale, falling back to English.
+func Translator(locale string) ut.Translator {
+	trans, _ := translators.GetTranslator(locale)
+	return trans
+}
+
+// TranslatorFor picks the translator for the request from its Accept-Language header.
+func TranslatorFor(r *http.Request) ut.Translator {
+	trans, _ := translators.FindTranslator(acceptedLanguages(r.Header.Get("Accept-Language"))...)
+	return trans
+}
+
+// parses an Accept-Language header into locale names in order of preference,
+// adding the base language after each regional one (fr-CH -> fr_CH, fr)
+func acceptedLanguages(header string) []string {
+	type lang struct {
+		tag string
+		q   float64
+	}
+	var langs []lang
+	for _, part := range strings.Split(header, ",") {
+		tag, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		q := 1.0
+		if v, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			if parsed, err := strconv.ParseFloat(v, 64); err == nil {
+				q = parsed
+			}
+		}
+		if tag != "" && tag != "*" && q > 0 {
+			langs = append(langs, lang{tag: tag, q: q})
+		}
+	}
+	sort.SliceStable(langs, func(i, j int) bool { return langs[i].q > langs[j].q })
+
+	var names []string
+	for _, l := range langs {
+		name := strings.ReplaceAll(l.tag, "-", "_")
+		base, region, hasRegion := strings.Cut(name, "_")
+		if hasRegion {
+			names = append(names, strings.ToLower(base)+"_"+strings.ToUpper(region))
+		}
+		names = append(names, strings.ToLower(base))
+	}
+	return append(names, defaultLocale)
+}
+
+// returns the message for a field error in the translator's locale. Length tags pick
+// the variant matching the field kind and field-comparison tags get the JSON name of
+// the other field as parameter.
+func translateFieldError(trans ut.Translator, e validator.FieldError, param string) string {
+	key := e.Tag()
+	switch key {
+	case "min", "max", "len":
+		switch e.Kind() {
+		case reflect.Slice, reflect.Map, reflect.Array:
+			key += "_items"
+		case reflect.String:
+		default:
+			key += "_number"
+		}
+	}
+
+	for _, t := range []ut.Translator{trans, Translator(defaultLocale)} {
+		if msg, err := translate(t, key, param); err == nil {
+			return msg
+		}
+	}
+	if msg, err := trans.T("invalid"); err == nil {
+		return msg
+	}
+	return "Invalid value"
+}
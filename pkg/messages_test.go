@@ -0,0 +1,46 @@
+package pkg
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestAcceptedLanguages(t *testing.T) {
+	tests := []struct {
+		header string
+		want   []string
+	}{
+		{"", []string{"en"}},
+		{"fr", []string{"fr", "en"}},
+		{"fr-CH, fr;q=0.9, en;q=0.8", []string{"fr_CH", "fr", "fr", "en", "en"}},
+		{"es;q=0.5, fr", []string{"fr", "es", "en"}},
+		{"EN-gb", []string{"en_GB", "en", "en"}},
+		{"*, de;q=0.7", []string{"de", "en"}},
+		{"fr;q=0, es", []string{"es", "en"}},
+		{"fr;q=abc", []string{"fr", "en"}},
+		{" , es ,", []string{"es", "en"}},
+	}
+	for _, tt := range tests {
+		if got := acceptedLanguages(tt.header); !slices.Equal(got, tt.want) {
+			t.Errorf("acceptedLanguages(%q) = %v, want %v", tt.header, got, tt.want)
+		}
+	}
+}
+
+func TestValidationMessagePadsParams(t *testing.T) {
+	tests := []struct {
+		key    string
+		params []string
+		want   string
+	}{
+		{"required", nil, "Missing required field"},
+		{"min", []string{"3"}, "Must be at least 3 characters"},
+		{"min", nil, "Must be at least  characters"},
+		{"no_such_tag", nil, "no_such_tag"},
+	}
+	for _, tt := range tests {
+		if got := ValidationMessage(t.Context(), tt.key, tt.params...); got != tt.want {
+			t.Errorf("ValidationMessage(%q, %v) = %q, want %q", tt.key, tt.params, got, tt.want)
+		}
+	}
+}
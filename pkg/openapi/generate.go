@@ -0,0 +1,210 @@
+package openapi
+
+import (
+	"net/http"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// Route documents an endpoint, attach it to the handler with Describe.
+type Route struct {
+	ID      string
+	Summary string
+	Tags    []string
+	// request DTO, its json fields form the body and `query`/`path` fields the parameters
+	Request any
+	// value of `data` in the success envelope, nil when there is none
+	Response any
+	// success status code, 200 when zero
+	Status int
+	// requires the access_token cookie
+	Auth bool
+	// error responses specific to the route, description by status code
+	Errors map[int]string
+}
+
+// documented is the handler returned by Describe
+type documented struct {
+	http.Handler
+	route Route
+}
+
+// Describe attaches the route documentation to a handler, it serves requests unchanged.
+//
+//	r.Method(http.MethodPost, "/signup", openapi.Describe(openapi.Route{...}, api.HandlerFunc(h.Signup)))
+func Describe(route Route, h http.Handler) http.Handler {
+	return &documented{Handler: h, route: route}
+}
+
+var (
+	// {id} or {id:[0-9]+} in chi patterns
+	pathParamRegex = regexp.MustCompile(`\{([^}:]+)(:[^}]*)?\}`)
+	nonWordRegex   = regexp.MustCompile(`[^A-Za-z0-9]+`)
+)
+
+// Generate builds the document for every route registered on r. Servers are
+// left to the caller since they depend on where the API is deployed.
+func Generate(r chi.Routes, info Info) (*Document, error) {
+	reg := newSchemaRegistry()
+	doc := &Document{
+		OpenAPI: Version,
+		Info:    info,
+		Paths:   map[string]*PathItem{},
+		Components: Components{
+			Schemas: reg.schemas,
+			SecuritySchemes: map[string]SecurityScheme{
+				"cookieAuth": {Type: "apiKey", In: "cookie", Name: "access_token"},
+			},
+		},
+	}
+	reg.schemas["Error"] = errorSchema()
+
+	err := chi.Walk(r, func(method string, pattern string, handler http.Handler, _ ...func(http.Handler) http.Handler) error {
+		var route Route
+		if d, ok := handler.(*documented); ok {
+			route = d.route
+		}
+		path := pathParamRegex.ReplaceAllString(strings.TrimSuffix(pattern, "/*"), "{$1}")
+		if path != "/" {
+			path = strings.TrimSuffix(path, "/")
+		}
+		item, ok := doc.Paths[path]
+		if !ok {
+			item = &PathItem{}
+			doc.Paths[path] = item
+		}
+		(*item)[strings.ToLower(method)] = reg.operation(method, path, route)
+		return nil
+	})
+	return doc, err
+}
+
+func (reg *schemaRegistry) operation(method, path string, route Route) *Operation {
+	op := &Operation{
+		OperationID: route.ID,
+		Summary:     route.Summary,
+		Tags:        route.Tags,
+		Responses:   map[string]Response{},
+	}
+	if op.OperationID == "" {
+		op.OperationID = strings.Trim(nonWordRegex.ReplaceAllString(strings.ToLower(method)+"_"+path, "_"), "_")
+	}
+
+	// path parameters from the pattern, typed by the request's `path` fields
+	var reqType reflect.Type
+	if route.Request != nil {
+		reqType = reflect.TypeOf(route.Request)
+		for reqType.Kind() == reflect.Ptr {
+			reqType = reqType.Elem()
+		}
+	}
+	for _, m := range pathParamRegex.FindAllStringSubmatch(path, -1) {
+		op.Parameters = append(op.Parameters, Parameter{
+			Name: m[1], In: "path", Required: true, Schema: reg.paramSchema(reqType, "path", m[1]),
+		})
+	}
+
+	if reqType != nil && reqType.Kind() == reflect.Struct {
+		for _, field := range reflect.VisibleFields(reqType) {
+			name := field.Tag.Get("query")
+			if name == "" || !field.IsExported() {
+				continue
+			}
+			schema := reg.schemaFor(field.Type)
+			required := applyValidateTag(schema, field.Type, field.Tag.Get("validate"))
+			op.Parameters = append(op.Parameters, Parameter{Name: name, In: "query", Required: required, Schema: schema})
+		}
+
+		body := reg.structSchema(reqType)
+		if len(body.Properties) > 0 && method != http.MethodGet && method != http.MethodDelete {
+			op.RequestBody = &RequestBody{
+				Required: true,
+				Content:  map[string]MediaType{"application/json": {Schema: reg.schemaFor(reqType)}},
+			}
+			op.Responses["413"] = errorResponse("Request body too large")
+			op.Responses["415"] = errorResponse("Unsupported content type")
+		}
+		op.Responses["400"] = errorResponse("Malformed request")
+		op.Responses["422"] = errorResponse("Validation failed, `errors` maps fields to messages")
+	}
+
+	status := route.Status
+	if status == 0 {
+		status = http.StatusOK
+	}
+	success := Response{Description: http.StatusText(status)}
+	if status != http.StatusNoContent {
+		envelope := &Schema{
+			Type:     "object",
+			Required: []string{"success"},
+			Properties: map[string]*Schema{
+				"success": {Type: "boolean"},
+				"message": {Type: "string"},
+			},
+		}
+		if route.Response != nil {
+			envelope.Properties["data"] = reg.schemaFor(reflect.TypeOf(route.Response))
+		}
+		success.Content = map[string]MediaType{"application/json": {Schema: envelope}}
+	}
+	op.Responses[strconv.Itoa(status)] = success
+
+	if route.Auth {
+		op.Security = []map[string][]string{{"cookieAuth": {}}}
+		op.Responses["401"] = errorResponse("Missing or invalid access token")
+	}
+	for status, description := range route.Errors {
+		op.Responses[strconv.Itoa(status)] = errorResponse(description)
+	}
+	// api.Negotiate rejects every route when no Accept type can be served
+	op.Responses["406"] = errorResponse("Not acceptable")
+	op.Responses["500"] = errorResponse("Internal server error")
+
+	sort.Slice(op.Parameters, func(i, j int) bool {
+		if op.Parameters[i].In != op.Parameters[j].In {
+			return op.Parameters[i].In < op.Parameters[j].In
+		}
+		return op.Parameters[i].Name < op.Parameters[j].Name
+	})
+	return op
+}
+
+// schema of a path parameter, from the request field bound to it or a string
+func (reg *schemaRegistry) paramSchema(reqType reflect.Type, in, name string) *Schema {
+	if reqType != nil && reqType.Kind() == reflect.Struct {
+		for _, field := range reflect.VisibleFields(reqType) {
+			if field.Tag.Get(in) == name {
+				schema := reg.schemaFor(field.Type)
+				applyValidateTag(schema, field.Type, field.Tag.Get("validate"))
+				return schema
+			}
+		}
+	}
+	return &Schema{Type: "string"}
+}
+
+func errorSchema() *Schema {
+	return &Schema{
+		Type:     "object",
+		Required: []string{"success"},
+		Properties: map[string]*Schema{
+			"success": {Type: "boolean"},
+			"message": {Type: "string"},
+			"errors":  {Type: "object", AdditionalProperties: &Schema{Type: "string"}},
+		},
+	}
+}
+
+func errorResponse(description string) Response {
+	return Response{
+		Description: description,
+		Content: map[string]MediaType{
+			"application/json": {Schema: &Schema{Ref: "#/components/schemas/Error"}},
+		},
+	}
+}
@@ -0,0 +1,235 @@
+package openapi
+
+import (
+	"mime/multipart"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	timeType       = reflect.TypeOf(time.Time{})
+	fileHeaderType = reflect.TypeOf(multipart.FileHeader{})
+)
+
+// patterns for the custom validation tags of pkg
+var tagPatterns = map[string]string{
+	"e164": `^\+[1-9]?[0-9]{7,14}$`,
+	"slug": `^[a-z0-9]+(?:-[a-z0-9]+)*$`,
+}
+
+var tagFormats = map[string]string{
+	"email": "email",
+	"url":   "uri",
+	"uri":   "uri",
+	"uuid":  "uuid",
+}
+
+// schemaRegistry builds component schemas for Go types
+type schemaRegistry struct {
+	schemas map[string]*Schema
+	names   map[reflect.Type]string
+}
+
+func newSchemaRegistry() *schemaRegistry {
+	return &schemaRegistry{schemas: map[string]*Schema{}, names: map[reflect.Type]string{}}
+}
+
+// returns the schema for t, registering named structs as components
+func (reg *schemaRegistry) schemaFor(t reflect.Type) *Schema {
+	if t.Kind() == reflect.Ptr {
+		return nullable(reg.schemaFor(t.Elem()))
+	}
+
+	switch {
+	case t == timeType || isTimestampType(t):
+		return &Schema{Type: "string", Format: "date-time"}
+	case t == fileHeaderType:
+		return &Schema{Type: "string", Format: "binary"}
+	}
+
+	switch t.Kind() {
+	case reflect.String:
+		return &Schema{Type: "string"}
+	case reflect.Bool:
+		return &Schema{Type: "boolean"}
+	case reflect.Int8, reflect.Int16, reflect.Int32, reflect.Uint8, reflect.Uint16:
+		return &Schema{Type: "integer", Format: "int32"}
+	case reflect.Int, reflect.Int64, reflect.Uint, reflect.Uint32, reflect.Uint64:
+		return &Schema{Type: "integer", Format: "int64"}
+	case reflect.Float32:
+		return &Schema{Type: "number", Format: "float"}
+	case reflect.Float64:
+		return &Schema{Type: "number", Format: "double"}
+	case reflect.Slice, reflect.Array:
+		return &Schema{Type: "array", Items: reg.schemaFor(t.Elem())}
+	case reflect.Map:
+		return &Schema{Type: "object", AdditionalProperties: reg.schemaFor(t.Elem())}
+	case reflect.Struct:
+		if t.Name() == "" {
+			return reg.structSchema(t)
+		}
+		return &Schema{Ref: "#/components/schemas/" + reg.register(t)}
+	default:
+		return &Schema{}
+	}
+}
+
+// pgtype.Timestamp and similar types marshal to a JSON date-time
+func isTimestampType(t reflect.Type) bool {
+	return t.Kind() == reflect.Struct && strings.HasSuffix(t.PkgPath(), "pgx/v5/pgtype") &&
+		strings.HasPrefix(t.Name(), "Timestamp")
+}
+
+func (reg *schemaRegistry) register(t reflect.Type) string {
+	if name, ok := reg.names[t]; ok {
+		return name
+	}
+	name := t.Name()
+	if _, taken := reg.schemas[name]; taken {
+		// same type name in another package, qualify it
+		name = t.PkgPath()[strings.LastIndex(t.PkgPath(), "/")+1:] + "." + name
+	}
+	reg.names[t] = name
+	reg.schemas[name] = &Schema{} // placeholder for recursive types
+	*reg.schemas[name] = *reg.structSchema(t)
+	return name
+}
+
+// builds the object schema of a struct from its json and validate tags.
+// Fields bound from the query string or path are left out of the body schema.
+func (reg *schemaRegistry) structSchema(t reflect.Type) *Schema {
+	s := &Schema{Type: "object", Properties: map[string]*Schema{}}
+	for _, field := range reflect.VisibleFields(t) {
+		if !field.IsExported() || field.Anonymous {
+			continue
+		}
+		if field.Tag.Get("query") != "" || field.Tag.Get("path") != "" {
+			continue
+		}
+		name := jsonName(field)
+		if name == "" {
+			continue
+		}
+		prop := reg.schemaFor(field.Type)
+		s.Properties[name] = prop
+		if applyValidateTag(prop, field.Type, field.Tag.Get("validate")) {
+			s.Required = append(s.Required, name)
+		}
+	}
+	return s
+}
+
+// returns the JSON name of a field, "" when it is not serialized
+func jsonName(field reflect.StructField) string {
+	tag := field.Tag.Get("json")
+	if tag == "-" {
+		return ""
+	}
+	name, _, _ := strings.Cut(tag, ",")
+	if name == "" {
+		name = field.Name
+	}
+	return name
+}
+
+// applies the validate tag rules to the schema and reports whether the field is required
+func applyValidateTag(s *Schema, t reflect.Type, tag string) bool {
+	if tag == "" || tag == "-" {
+		return false
+	}
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	required := false
+	for _, rule := range strings.Split(tag, ",") {
+		name, param, _ := strings.Cut(rule, "=")
+		switch name {
+		case "required":
+			required = true
+		case "min", "max", "len", "gte", "lte", "gt", "lt":
+			applyBound(s, t, name, param)
+		case "oneof":
+			for _, v := range strings.Fields(param) {
+				s.Enum = append(s.Enum, enumValue(t, v))
+			}
+		case "dive":
+			// rules after dive apply to the elements
+			return required
+		default:
+			if format, ok := tagFormats[name]; ok {
+				s.Format = format
+			}
+			if pattern, ok := tagPatterns[name]; ok {
+				s.Pattern = pattern
+			}
+		}
+	}
+	return required
+}
+
+func applyBound(s *Schema, t reflect.Type, rule, param string) {
+	n, err := strconv.ParseFloat(param, 64)
+	if err != nil {
+		return
+	}
+	i := int(n)
+	switch t.Kind() {
+	case reflect.String:
+		switch rule {
+		case "min", "gte":
+			s.MinLength = &i
+		case "max", "lte":
+			s.MaxLength = &i
+		case "len":
+			s.MinLength, s.MaxLength = &i, &i
+		}
+	case reflect.Slice, reflect.Array, reflect.Map:
+		switch rule {
+		case "min", "gte":
+			s.MinItems = &i
+		case "max", "lte":
+			s.MaxItems = &i
+		case "len":
+			s.MinItems, s.MaxItems = &i, &i
+		}
+	default:
+		switch rule {
+		case "min", "gte":
+			s.Minimum = &n
+		case "max", "lte":
+			s.Maximum = &n
+		case "len":
+			s.Minimum, s.Maximum = &n, &n
+		case "gt":
+			s.ExclusiveMinimum = &n
+		case "lt":
+			s.ExclusiveMaximum = &n
+		}
+	}
+}
+
+func enumValue(t reflect.Type, v string) any {
+	switch t.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		if n, err := strconv.ParseInt(v, 10, 64); err == nil {
+			return n
+		}
+	}
+	return v
+}
+
+// makes the schema accept null, using a type list as JSON Schema 2020-12 does.
+// A $ref can't carry a type, so it becomes a oneOf with the null type.
+func nullable(s *Schema) *Schema {
+	if s.Ref != "" {
+		return &Schema{OneOf: []*Schema{s, {Type: "null"}}}
+	}
+	if typ, ok := s.Type.(string); ok {
+		s.Type = []string{typ, "null"}
+	}
+	return s
+}
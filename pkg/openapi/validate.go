@@ -0,0 +1,517 @@
+package openapi
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"go-rest-template/pkg"
+	"go-rest-template/pkg/api"
+	"go-rest-template/pkg/config"
+	"go-rest-template/pkg/logger"
+	"io"
+	"mime"
+	"net/http"
+	"net/mail"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// ValidatorOptions controls the validation middleware.
+type ValidatorOptions struct {
+	// also validate JSON responses against the documented schema and log mismatches,
+	// meant for development and tests as it buffers every response
+	ValidateResponses bool
+}
+
+type compiledOperation struct {
+	method    string
+	path      *regexp.Regexp
+	params    []string
+	operation *Operation
+}
+
+// requestValidator checks requests against the operations of a document
+type requestValidator struct {
+	doc        *Document
+	operations []compiledOperation
+	opts       ValidatorOptions
+}
+
+var uuidRegex = regexp.MustCompile(`^[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}$`)
+
+// ValidateRequests returns a middleware validating path, query and header parameters and
+// JSON bodies against the document before the handler runs. Invalid requests get a 422 in
+// the same shape as api.ValidationErrors; undocumented routes are passed through.
+func ValidateRequests(doc *Document, opts ValidatorOptions) func(http.Handler) http.Handler {
+	v := &requestValidator{doc: doc, opts: opts}
+	for path, item := range doc.Paths {
+		var params []string
+		var expr strings.Builder
+		last := 0
+		for _, m := range pathParamRegex.FindAllStringSubmatchIndex(path, -1) {
+			expr.WriteString(regexp.QuoteMeta(path[last:m[0]]))
+			expr.WriteString("([^/]+)")
+			params = append(params, path[m[2]:m[3]])
+			last = m[1]
+		}
+		expr.WriteString(regexp.QuoteMeta(path[last:]))
+		re := regexp.MustCompile("^" + expr.String() + "$")
+		for method, op := range *item {
+			v.operations = append(v.operations, compiledOperation{
+				method: strings.ToUpper(method), path: re, params: params, operation: op,
+			})
+		}
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			op, pathValues := v.match(r)
+			if op == nil {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			errs, err := v.validateRequest(r, op, pathValues)
+			if err != nil {
+				api.RenderError(w, r, err)
+				return
+			}
+			if len(errs) > 0 {
+				api.ValidationErrors(w, errs)
+				return
+			}
+
+			if !v.opts.ValidateResponses {
+				next.ServeHTTP(w, r)
+				return
+			}
+			rec := &responseRecorder{ResponseWriter: w, status: http.StatusOK}
+			next.ServeHTTP(rec, r)
+			v.validateResponse(r, op, rec)
+			rec.flush()
+		})
+	}
+}
+
+func (v *requestValidator) match(r *http.Request) (*Operation, map[string]string) {
+	for _, c := range v.operations {
+		if c.method != r.Method {
+			continue
+		}
+		m := c.path.FindStringSubmatch(r.URL.Path)
+		if m == nil {
+			continue
+		}
+		values := map[string]string{}
+		for i, name := range c.params {
+			values[name], _ = url.PathUnescape(m[i+1])
+		}
+		return c.operation, values
+	}
+	return nil, nil
+}
+
+func (v *requestValidator) validateRequest(r *http.Request, op *Operation, pathValues map[string]string) (map[string]string, error) {
+	// field errors use the same localized messages as pkg.BindAndValidate
+	ctx := pkg.WithTranslator(r.Context(), pkg.TranslatorFor(r))
+	errs := map[string]string{}
+	query := r.URL.Query()
+	for _, p := range op.Parameters {
+		var raw string
+		var present bool
+		switch p.In {
+		case "path":
+			raw, present = pathValues[p.Name]
+		case "query":
+			present = query.Has(p.Name)
+			raw = query.Get(p.Name)
+		case "header":
+			raw = r.Header.Get(p.Name)
+			present = raw != ""
+		default:
+			continue
+		}
+		if !present {
+			if p.Required {
+				errs[p.Name] = pkg.ValidationMessage(ctx, "required")
+			}
+			continue
+		}
+		v.validateValue(ctx, errs, p.Name, paramValue(raw, v.resolve(p.Schema)), p.Schema)
+	}
+
+	if op.RequestBody == nil {
+		return errs, nil
+	}
+	media, ok := op.RequestBody.Content["application/json"]
+	contentType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if !ok || contentType != "application/json" {
+		// other content types are checked by the handlers' binding
+		return errs, nil
+	}
+
+	content, err := io.ReadAll(http.MaxBytesReader(nil, r.Body, config.APP().MAX_BODY_BYTES))
+	if err != nil {
+		var maxErr *http.MaxBytesError
+		if errors.As(err, &maxErr) {
+			return nil, api.ErrStatus(http.StatusRequestEntityTooLarge, fmt.Sprintf("request body must not be larger than %d bytes", maxErr.Limit))
+		}
+		return nil, err
+	}
+	r.Body = io.NopCloser(bytes.NewReader(content))
+	if len(bytes.TrimSpace(content)) == 0 {
+		if op.RequestBody.Required {
+			return nil, api.ErrBadRequest("request body is empty")
+		}
+		return errs, nil
+	}
+
+	body, err := decodeJSON(content)
+	if err != nil {
+		return nil, api.ErrBadRequest(err.Error())
+	}
+	v.validateValue(ctx, errs, "", body, media.Schema)
+	return errs, nil
+}
+
+func decodeJSON(content []byte) (any, error) {
+	dec := json.NewDecoder(bytes.NewReader(content))
+	dec.UseNumber()
+	var value any
+	if err := dec.Decode(&value); err != nil {
+		return nil, err
+	}
+	return value, nil
+}
+
+// converts a raw parameter to the JSON type of its schema so it can be validated
+func paramValue(raw string, schema *Schema) any {
+	switch schemaType(schema) {
+	case "integer", "number":
+		return json.Number(raw)
+	case "boolean":
+		if b, err := strconv.ParseBool(raw); err == nil {
+			return b
+		}
+	}
+	return raw
+}
+
+// resolves a $ref to its component schema
+func (v *requestValidator) resolve(s *Schema) *Schema {
+	for s != nil && s.Ref != "" {
+		name := strings.TrimPrefix(s.Ref, "#/components/schemas/")
+		s = v.doc.Components.Schemas[name]
+	}
+	return s
+}
+
+// returns the non-null type of the schema
+func schemaType(s *Schema) string {
+	if s == nil {
+		return ""
+	}
+	switch t := s.Type.(type) {
+	case string:
+		return t
+	case []string:
+		for _, typ := range t {
+			if typ != "null" {
+				return typ
+			}
+		}
+	case []any:
+		for _, typ := range t {
+			if str, ok := typ.(string); ok && str != "null" {
+				return str
+			}
+		}
+	}
+	return ""
+}
+
+func allowsNull(s *Schema) bool {
+	switch t := s.Type.(type) {
+	case string:
+		return t == "null"
+	case []string:
+		for _, typ := range t {
+			if typ == "null" {
+				return true
+			}
+		}
+	case []any:
+		for _, typ := range t {
+			if typ == "null" {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func joinPath(path, key string) string {
+	if path == "" {
+		return key
+	}
+	return path + "." + key
+}
+
+// validates value against exactly one of the schemas. When none matches the errors
+// of the first schema that isn't the null type are reported.
+func (v *requestValidator) validateOneOf(ctx context.Context, errs map[string]string, path string, value any, schemas []*Schema) {
+	var first map[string]string
+	matches := 0
+	for _, schema := range schemas {
+		branch := map[string]string{}
+		v.validateValue(ctx, branch, path, value, schema)
+		if len(branch) == 0 {
+			matches++
+		} else if first == nil && schemaType(v.resolve(schema)) != "null" {
+			first = branch
+		}
+	}
+	switch {
+	case matches == 0:
+		for k, msg := range first {
+			if _, exists := errs[k]; !exists {
+				errs[k] = msg
+			}
+		}
+	case matches > 1:
+		key := path
+		if key == "" {
+			key = "body"
+		}
+		if _, exists := errs[key]; !exists {
+			errs[key] = pkg.ValidationMessage(ctx, "one_schema")
+		}
+	}
+}
+
+// validates value against the schema, adding messages keyed by JSON path to errs.
+// Messages are in the locale of the translator carried by ctx.
+func (v *requestValidator) validateValue(ctx context.Context, errs map[string]string, path string, value any, schema *Schema) {
+	s := v.resolve(schema)
+	if s == nil {
+		return
+	}
+	fail := func(tag string, params ...string) {
+		key := path
+		if key == "" {
+			key = "body"
+		}
+		if _, exists := errs[key]; !exists {
+			errs[key] = pkg.ValidationMessage(ctx, tag, params...)
+		}
+	}
+
+	if len(s.OneOf) > 0 {
+		v.validateOneOf(ctx, errs, path, value, s.OneOf)
+		return
+	}
+
+	if value == nil {
+		if !allowsNull(s) && schemaType(s) != "" {
+			fail("not_null")
+		}
+		return
+	}
+
+	switch schemaType(s) {
+	case "object":
+		obj, ok := value.(map[string]any)
+		if !ok {
+			fail("type_object")
+			return
+		}
+		for _, name := range s.Required {
+			if _, ok := obj[name]; !ok {
+				errs[joinPath(path, name)] = pkg.ValidationMessage(ctx, "required")
+			}
+		}
+		for name, prop := range s.Properties {
+			if fieldValue, ok := obj[name]; ok {
+				v.validateValue(ctx, errs, joinPath(path, name), fieldValue, prop)
+			}
+		}
+		if s.AdditionalProperties != nil {
+			for name, fieldValue := range obj {
+				if _, known := s.Properties[name]; !known {
+					v.validateValue(ctx, errs, joinPath(path, name), fieldValue, s.AdditionalProperties)
+				}
+			}
+		}
+	case "array":
+		items, ok := value.([]any)
+		if !ok {
+			fail("type_array")
+			return
+		}
+		if s.MinItems != nil && len(items) < *s.MinItems {
+			fail("min_items", strconv.Itoa(*s.MinItems))
+		}
+		if s.MaxItems != nil && len(items) > *s.MaxItems {
+			fail("max_items", strconv.Itoa(*s.MaxItems))
+		}
+		for i, item := range items {
+			v.validateValue(ctx, errs, fmt.Sprintf("%s[%d]", path, i), item, s.Items)
+		}
+	case "string":
+		str, ok := value.(string)
+		if !ok {
+			fail("type_string")
+			return
+		}
+		length := utf8.RuneCountInString(str)
+		if s.MinLength != nil && length < *s.MinLength {
+			fail("min", strconv.Itoa(*s.MinLength))
+		}
+		if s.MaxLength != nil && length > *s.MaxLength {
+			fail("max", strconv.Itoa(*s.MaxLength))
+		}
+		if s.Pattern != "" {
+			if re, err := regexp.Compile(s.Pattern); err == nil && !re.MatchString(str) {
+				fail("invalid")
+			}
+		}
+		if tag := checkFormat(s.Format, str); tag != "" {
+			fail(tag)
+		}
+	case "integer", "number":
+		num, ok := value.(json.Number)
+		if !ok {
+			fail("number")
+			return
+		}
+		n, err := num.Float64()
+		if err != nil {
+			fail("number")
+			return
+		}
+		if schemaType(s) == "integer" {
+			if _, err := num.Int64(); err != nil {
+				fail("type_integer")
+				return
+			}
+		}
+		switch {
+		case s.Minimum != nil && n < *s.Minimum:
+			fail("gte", fmt.Sprint(*s.Minimum))
+		case s.Maximum != nil && n > *s.Maximum:
+			fail("lte", fmt.Sprint(*s.Maximum))
+		case s.ExclusiveMinimum != nil && n <= *s.ExclusiveMinimum:
+			fail("gt", fmt.Sprint(*s.ExclusiveMinimum))
+		case s.ExclusiveMaximum != nil && n >= *s.ExclusiveMaximum:
+			fail("lt", fmt.Sprint(*s.ExclusiveMaximum))
+		}
+	case "boolean":
+		if _, ok := value.(bool); !ok {
+			fail("boolean")
+			return
+		}
+	case "null":
+		fail("type_null")
+		return
+	}
+
+	if len(s.Enum) > 0 && !inEnum(value, s.Enum) {
+		fail("oneof", enumList(s.Enum))
+	}
+}
+
+func inEnum(value any, enum []any) bool {
+	for _, e := range enum {
+		if fmt.Sprint(e) == fmt.Sprint(value) {
+			return true
+		}
+	}
+	return false
+}
+
+// returns the message tag for a string not matching its format, "" when it matches
+func checkFormat(format, s string) string {
+	switch format {
+	case "email":
+		if addr, err := mail.ParseAddress(s); err != nil || addr.Address != s {
+			return "email"
+		}
+	case "uuid":
+		if !uuidRegex.MatchString(s) {
+			return "uuid"
+		}
+	case "uri":
+		if u, err := url.Parse(s); err != nil || !u.IsAbs() {
+			return "url"
+		}
+	case "date-time":
+		if _, err := time.Parse(time.RFC3339, s); err != nil {
+			return "datetime"
+		}
+	}
+	return ""
+}
+
+// formats enum values like the oneof tag's parameter
+func enumList(enum []any) string {
+	values := make([]string, len(enum))
+	for i, e := range enum {
+		values[i] = fmt.Sprint(e)
+	}
+	return strings.Join(values, " ")
+}
+
+// responseRecorder buffers the response so it can be validated before being sent
+type responseRecorder struct {
+	http.ResponseWriter
+	status int
+	body   bytes.Buffer
+}
+
+func (rec *responseRecorder) WriteHeader(status int) {
+	rec.status = status
+}
+
+func (rec *responseRecorder) Write(b []byte) (int, error) {
+	return rec.body.Write(b)
+}
+
+func (rec *responseRecorder) Unwrap() http.ResponseWriter {
+	return rec.ResponseWriter
+}
+
+func (rec *responseRecorder) flush() {
+	rec.ResponseWriter.WriteHeader(rec.status)
+	rec.ResponseWriter.Write(rec.body.Bytes())
+}
+
+// logs responses that don't match the documented status codes or schema
+func (v *requestValidator) validateResponse(r *http.Request, op *Operation, rec *responseRecorder) {
+	log := logger.FromContext(r.Context())
+	resp, ok := op.Responses[strconv.Itoa(rec.status)]
+	if !ok {
+		log.Error("response status is not documented", logger.Fields{"status": rec.status})
+		return
+	}
+	contentType, _, _ := mime.ParseMediaType(rec.Header().Get("Content-Type"))
+	media, ok := resp.Content[contentType]
+	if !ok || contentType != "application/json" {
+		return
+	}
+	body, err := decodeJSON(rec.body.Bytes())
+	if err != nil {
+		log.Error("response is not valid JSON", logger.Fields{"status": rec.status, "error": err})
+		return
+	}
+	errs := map[string]string{}
+	v.validateValue(context.Background(), errs, "", body, media.Schema)
+	if len(errs) > 0 {
+		log.Error("response does not match the OpenAPI schema", logger.Fields{"status": rec.status, "errors": errs})
+	}
+}
@@ -0,0 +1,148 @@
+package security
+
+import (
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"go-rest-template/pkg/config"
+
+	"github.com/go-chi/cors"
+)
+
+// CORSPolicy decides which cross-origin requests browsers may make
+type CORSPolicy struct {
+	// exact origins like https://app.example.com, https://*.example.com for any
+	// subdomain, or * for any origin (only without credentials)
+	AllowedOrigins   []string
+	AllowedMethods   []string
+	AllowedHeaders   []string
+	ExposedHeaders   []string
+	AllowCredentials bool
+	// how long browsers may cache a preflight response
+	MaxAge time.Duration
+}
+
+type corsGroup struct {
+	prefix string
+	policy CORSPolicy
+}
+
+var (
+	corsMu     sync.RWMutex
+	corsGroups []corsGroup
+)
+
+// returns the policy of the CORS_* settings. CLIENT_URL is always allowed.
+func configCORSPolicy(c *config.Config) CORSPolicy {
+	origins := slices.Clone(c.CORS_ALLOWED_ORIGINS)
+	if c.CLIENT_URL != "" {
+		origins = append(origins, c.CLIENT_URL)
+	}
+	return CORSPolicy{
+		AllowedOrigins:   origins,
+		AllowedMethods:   c.CORS_ALLOWED_METHODS,
+		AllowedHeaders:   c.CORS_ALLOWED_HEADERS,
+		ExposedHeaders:   c.CORS_EXPOSED_HEADERS,
+		AllowCredentials: c.CORS_ALLOW_CREDENTIALS,
+		MaxAge:           c.CORS_MAX_AGE,
+	}
+}
+
+// registers a policy for the routes under prefix, replacing the configured one there.
+// The longest matching prefix wins. Register policies before mounting CORS.
+func RegisterCORS(prefix string, p CORSPolicy) {
+	corsMu.Lock()
+	defer corsMu.Unlock()
+	corsGroups = append(corsGroups, corsGroup{prefix: strings.TrimSuffix(prefix, "/"), policy: p})
+	slices.SortStableFunc(corsGroups, func(a, b corsGroup) int { return len(b.prefix) - len(a.prefix) })
+}
+
+// the CORS handlers wrapping one next handler, one per registered group and one
+// for the configured policy
+type corsHandlers struct {
+	prefixes []string
+	groups   []http.Handler
+	fallback http.Handler
+}
+
+func newCORSHandlers(next http.Handler, c *config.Config) *corsHandlers {
+	corsMu.RLock()
+	defer corsMu.RUnlock()
+	h := &corsHandlers{fallback: configCORSPolicy(c).build().Handler(next)}
+	for _, g := range corsGroups {
+		h.prefixes = append(h.prefixes, g.prefix)
+		h.groups = append(h.groups, g.policy.build().Handler(next))
+	}
+	return h
+}
+
+// returns the handler of the longest registered prefix of path
+func (h *corsHandlers) handlerFor(path string) http.Handler {
+	for i, prefix := range h.prefixes {
+		if path == prefix || strings.HasPrefix(path, prefix+"/") {
+			return h.groups[i]
+		}
+	}
+	return h.fallback
+}
+
+// Middleware that answers preflight requests and sets the CORS headers of the policy
+// registered for the request path, or of the configured policy. The handlers are
+// built once and again when the configuration is reloaded.
+func CORS() func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		var handlers atomic.Pointer[corsHandlers]
+		cfg := config.APP()
+		handlers.Store(newCORSHandlers(next, &cfg))
+		config.Subscribe(func(old, new *config.Config) {
+			handlers.Store(newCORSHandlers(next, new))
+		})
+
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			handlers.Load().handlerFor(r.URL.Path).ServeHTTP(w, r)
+		})
+	}
+}
+
+func (p CORSPolicy) build() *cors.Cors {
+	origins := make([]string, len(p.AllowedOrigins))
+	for i, o := range p.AllowedOrigins {
+		origins[i] = strings.ToLower(strings.TrimSuffix(o, "/"))
+	}
+	return cors.New(cors.Options{
+		// an empty list allows no origin, unlike go-chi/cors where it allows all
+		AllowOriginFunc: func(r *http.Request, origin string) bool {
+			origin = strings.ToLower(origin)
+			for _, pattern := range origins {
+				if matchOrigin(pattern, origin) {
+					return true
+				}
+			}
+			return false
+		},
+		AllowedMethods:   p.AllowedMethods,
+		AllowedHeaders:   p.AllowedHeaders,
+		ExposedHeaders:   p.ExposedHeaders,
+		AllowCredentials: p.AllowCredentials,
+		MaxAge:           int(p.MaxAge.Seconds()),
+	})
+}
+
+// reports whether origin matches pattern, where https://*.example.com matches
+// subdomains of example.com at any depth but not example.com itself
+func matchOrigin(pattern, origin string) bool {
+	if pattern == "*" || pattern == origin {
+		return true
+	}
+	scheme, host, ok := strings.Cut(pattern, "://*.")
+	if !ok {
+		return false
+	}
+	rest, ok := strings.CutPrefix(origin, scheme+"://")
+	// the suffix has to follow a dot so https://*.example.com doesn't match https://badexample.com
+	return ok && strings.HasSuffix(rest, "."+host) && len(rest) > len(host)+1
+}
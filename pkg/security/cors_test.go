@@ -0,0 +1,41 @@
+package security
+
+import "testing"
+
+func TestMatchOrigin(t *testing.T) {
+	tests := []struct {
+		pattern, origin string
+		want            bool
+	}{
+		{"*", "https://anything.test", true},
+		{"https://app.example.com", "https://app.example.com", true},
+		{"https://app.example.com", "http://app.example.com", false},
+		{"https://*.example.com", "https://a.example.com", true},
+		{"https://*.example.com", "https://a.b.example.com", true},
+		{"https://*.example.com", "https://example.com", false},
+		{"https://*.example.com", "https://badexample.com", false},
+		{"https://*.example.com", "http://a.example.com", false},
+	}
+	for _, tt := range tests {
+		if got := matchOrigin(tt.pattern, tt.origin); got != tt.want {
+			t.Errorf("matchOrigin(%q, %q) = %v, want %v", tt.pattern, tt.origin, got, tt.want)
+		}
+	}
+}
+
+func TestHasDirective(t *testing.T) {
+	tests := []struct {
+		csp, name string
+		want      bool
+	}{
+		{"default-src 'self'; report-uri /r", "report-uri", true},
+		{"default-src 'self';REPORT-TO grp", "report-to", true},
+		{"default-src 'self' report-uri", "report-uri", false},
+		{"", "report-to", false},
+	}
+	for _, tt := range tests {
+		if got := hasDirective(tt.csp, tt.name); got != tt.want {
+			t.Errorf("hasDirective(%q, %q) = %v, want %v", tt.csp, tt.name, got, tt.want)
+		}
+	}
+}
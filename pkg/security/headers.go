@@ -0,0 +1,135 @@
+package security
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"go-rest-template/pkg/config"
+)
+
+// ReportPath is where browsers send Content-Security-Policy violation reports
+const ReportPath = "/api/csp-report"
+
+// Policy is the set of security headers sent with a response. Empty values leave
+// the header out.
+type Policy struct {
+	ContentSecurityPolicy string
+	// send the CSP as Content-Security-Policy-Report-Only, reporting violations without blocking
+	CSPReportOnly             bool
+	FrameOptions              string
+	ReferrerPolicy            string
+	PermissionsPolicy         string
+	CrossOriginOpenerPolicy   string
+	CrossOriginEmbedderPolicy string
+	CrossOriginResourcePolicy string
+	// Strict-Transport-Security max-age, 0 leaves the header out
+	HSTSMaxAge time.Duration
+}
+
+type contextKey struct{}
+
+// returns the policy built from the configuration. HSTS is included in production
+// and when the request came over TLS.
+func policyFor(r *http.Request) Policy {
+	cfg := config.APP()
+	p := Policy{
+		ContentSecurityPolicy:     cfg.SECURITY_CSP,
+		CSPReportOnly:             cfg.SECURITY_CSP_REPORT_ONLY,
+		FrameOptions:              cfg.SECURITY_FRAME_OPTIONS,
+		ReferrerPolicy:            cfg.SECURITY_REFERRER_POLICY,
+		PermissionsPolicy:         cfg.SECURITY_PERMISSIONS_POLICY,
+		CrossOriginOpenerPolicy:   cfg.SECURITY_COOP,
+		CrossOriginEmbedderPolicy: cfg.SECURITY_COEP,
+		CrossOriginResourcePolicy: cfg.SECURITY_CORP,
+	}
+	if cfg.GO_ENV == "production" || r.TLS != nil {
+		p.HSTSMaxAge = cfg.HSTS_MAX_AGE
+	}
+	return p
+}
+
+// Middleware that sets the security headers of the configured policy on every
+// response. Routes can change the policy with Override.
+func Headers(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		p := policyFor(r)
+		p.apply(w.Header())
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), contextKey{}, p)))
+	})
+}
+
+// Middleware that changes the policy for the routes it is used on, e.g. to allow
+// a CDN script on one page:
+//
+//	r.With(security.Override(func(p *security.Policy) {
+//		p.ContentSecurityPolicy = "default-src 'self'; script-src https://cdn.example.com"
+//	})).Get("/docs", docs)
+func Override(change func(p *Policy)) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			p, ok := r.Context().Value(contextKey{}).(Policy)
+			if !ok {
+				p = policyFor(r)
+			}
+			change(&p)
+			p.apply(w.Header())
+			next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), contextKey{}, p)))
+		})
+	}
+}
+
+// sets the policy's headers, removing those it leaves empty
+func (p Policy) apply(h http.Header) {
+	set := func(name, value string) {
+		if value == "" {
+			h.Del(name)
+			return
+		}
+		h.Set(name, value)
+	}
+
+	h.Set("X-Content-Type-Options", "nosniff")
+	csp := p.ContentSecurityPolicy
+	h.Del("Reporting-Endpoints")
+	if csp != "" {
+		// violations are reported to ReportPath unless the policy names its own endpoints
+		if !hasDirective(csp, "report-uri") {
+			csp += "; report-uri " + ReportPath
+		}
+		if !hasDirective(csp, "report-to") {
+			csp += "; report-to csp"
+			h.Set("Reporting-Endpoints", `csp="`+ReportPath+`"`)
+		}
+	}
+	if p.CSPReportOnly {
+		h.Del("Content-Security-Policy")
+		set("Content-Security-Policy-Report-Only", csp)
+	} else {
+		h.Del("Content-Security-Policy-Report-Only")
+		set("Content-Security-Policy", csp)
+	}
+	set("X-Frame-Options", p.FrameOptions)
+	set("Referrer-Policy", p.ReferrerPolicy)
+	set("Permissions-Policy", p.PermissionsPolicy)
+	set("Cross-Origin-Opener-Policy", p.CrossOriginOpenerPolicy)
+	set("Cross-Origin-Embedder-Policy", p.CrossOriginEmbedderPolicy)
+	set("Cross-Origin-Resource-Policy", p.CrossOriginResourcePolicy)
+	if p.HSTSMaxAge > 0 {
+		h.Set("Strict-Transport-Security", "max-age="+strconv.Itoa(int(p.HSTSMaxAge.Seconds()))+"; includeSubDomains")
+	} else {
+		h.Del("Strict-Transport-Security")
+	}
+}
+
+// reports whether a Content-Security-Policy sets the directive
+func hasDirective(csp, name string) bool {
+	for _, directive := range strings.Split(csp, ";") {
+		if fields := strings.Fields(directive); len(fields) > 0 && strings.EqualFold(fields[0], name) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,90 @@
+package security
+
+import (
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"go-rest-template/pkg/logger"
+)
+
+// clients tracked by one limiter, new clients are refused while it is full
+const maxRateClients = 10000
+
+type rateWindow struct {
+	start time.Time
+	count int
+}
+
+// counts requests per client IP in fixed windows
+type rateLimiter struct {
+	mu         sync.Mutex
+	limit      int
+	window     time.Duration
+	maxClients int
+	start      time.Time
+	clients    map[string]*rateWindow
+	// window start of the oldest client, a full table is only swept once it expired
+	oldest time.Time
+}
+
+// reports whether the client may make another request, and otherwise how long
+// until its window ends
+func (l *rateLimiter) allow(ip string, now time.Time) (bool, time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	// forget clients whose window ended, once per window or when the table is full
+	_, known := l.clients[ip]
+	if now.Sub(l.start) >= l.window || (!known && len(l.clients) >= l.maxClients && now.Sub(l.oldest) >= l.window) {
+		l.sweep(now)
+	}
+	w, ok := l.clients[ip]
+	if !ok && len(l.clients) >= l.maxClients {
+		return false, l.oldest.Add(l.window).Sub(now)
+	}
+	if !ok || now.Sub(w.start) >= l.window {
+		if len(l.clients) == 0 {
+			l.oldest = now
+		}
+		w = &rateWindow{start: now}
+		l.clients[ip] = w
+	}
+	if w.count >= l.limit {
+		return false, w.start.Add(l.window).Sub(now)
+	}
+	w.count++
+	return true, 0
+}
+
+func (l *rateLimiter) sweep(now time.Time) {
+	l.oldest = now
+	for key, w := range l.clients {
+		if now.Sub(w.start) >= l.window {
+			delete(l.clients, key)
+		} else if w.start.Before(l.oldest) {
+			l.oldest = w.start
+		}
+	}
+	l.start = now
+}
+
+// Middleware that allows each client IP limit requests per window and answers
+// 429 Too Many Requests with Retry-After beyond that. The IP is the socket peer
+// unless RealIP trusted the proxy headers. Counts are kept in memory,
+// per instance, for at most maxRateClients clients per window.
+func RateLimit(limit int, window time.Duration) func(http.Handler) http.Handler {
+	l := &rateLimiter{limit: limit, window: window, maxClients: maxRateClients, clients: map[string]*rateWindow{}}
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if ok, retry := l.allow(logger.RemoteIP(r), time.Now()); !ok {
+				seconds := int((retry + time.Second - 1) / time.Second)
+				w.Header().Set("Retry-After", strconv.Itoa(seconds))
+				w.WriteHeader(http.StatusTooManyRequests)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
@@ -0,0 +1,95 @@
+package security
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestRateLimit(t *testing.T) {
+	handler := RateLimit(2, time.Minute)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	tests := []struct {
+		remoteAddr string
+		status     int
+	}{
+		{"1.1.1.1:1000", http.StatusNoContent},
+		{"1.1.1.1:1001", http.StatusNoContent},
+		{"1.1.1.1:1002", http.StatusTooManyRequests},
+		{"2.2.2.2:1000", http.StatusNoContent},
+		{"1.1.1.1:1003", http.StatusTooManyRequests},
+	}
+	for i, tt := range tests {
+		r := httptest.NewRequest(http.MethodPost, "/", nil)
+		r.RemoteAddr = tt.remoteAddr
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, r)
+		if rec.Code != tt.status {
+			t.Errorf("request %d from %s: status %d, want %d", i, tt.remoteAddr, rec.Code, tt.status)
+		}
+		if tt.status == http.StatusTooManyRequests && rec.Header().Get("Retry-After") != "60" {
+			t.Errorf("request %d: Retry-After %q, want 60", i, rec.Header().Get("Retry-After"))
+		}
+	}
+}
+
+func TestRateLimiterWindows(t *testing.T) {
+	l := &rateLimiter{limit: 1, window: time.Minute, maxClients: 2, clients: map[string]*rateWindow{}}
+	start := time.Now()
+	steps := []struct {
+		ip    string
+		after time.Duration
+		want  bool
+	}{
+		{"a", 0, true},
+		{"a", time.Second, false},
+		{"b", time.Second, true},
+		// the table is full, new clients wait for the next window
+		{"c", 2 * time.Second, false},
+		{"a", time.Minute, true},
+		{"c", time.Minute + time.Second, true},
+	}
+	for i, s := range steps {
+		if got, _ := l.allow(s.ip, start.Add(s.after)); got != s.want {
+			t.Errorf("step %d (%s at %s): allowed %v, want %v", i, s.ip, s.after, got, s.want)
+		}
+	}
+}
+
+func TestRealIP(t *testing.T) {
+	tests := []struct {
+		name       string
+		remoteAddr string
+		xff, xri   string
+		want       string
+	}{
+		{"untrusted peer keeps its address", "1.2.3.4:5", "9.9.9.9", "8.8.8.8", "1.2.3.4:5"},
+		{"rightmost untrusted hop", "10.0.0.1:5", "9.9.9.9, 8.8.8.8, 10.0.0.2", "", "8.8.8.8"},
+		{"all hops trusted", "10.0.0.1:5", "10.0.0.3, 10.0.0.2", "", "10.0.0.3"},
+		{"X-Real-IP without X-Forwarded-For", "10.0.0.1:5", "", "7.7.7.7", "7.7.7.7"},
+		{"invalid hop", "10.0.0.1:5", "not-an-ip", "", "10.0.0.1:5"},
+		{"single trusted IP", "[::1]:5", "6.6.6.6", "", "6.6.6.6"},
+	}
+	var got string
+	handler := RealIP([]string{"10.0.0.0/8", "::1"})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = r.RemoteAddr
+	}))
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, "/", nil)
+			r.RemoteAddr = tt.remoteAddr
+			if tt.xff != "" {
+				r.Header.Set("X-Forwarded-For", tt.xff)
+			}
+			if tt.xri != "" {
+				r.Header.Set("X-Real-IP", tt.xri)
+			}
+			handler.ServeHTTP(httptest.NewRecorder(), r)
+			if got != tt.want {
+				t.Errorf("RemoteAddr %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,77 @@
+package security
+
+import (
+	"net"
+	"net/http"
+	"net/netip"
+	"strings"
+)
+
+// Middleware that sets r.RemoteAddr to the client address named by X-Forwarded-For
+// or, without it, X-Real-IP, only for requests whose peer is one of the trusted
+// proxies (IPs or CIDR ranges, see TRUSTED_PROXIES). Other requests keep their socket
+// peer, so clients can't pick their address. X-Forwarded-For is read from the right,
+// skipping trusted proxies.
+func RealIP(trusted []string) func(http.Handler) http.Handler {
+	var prefixes []netip.Prefix
+	for _, t := range trusted {
+		if p, err := netip.ParsePrefix(t); err == nil {
+			prefixes = append(prefixes, p.Masked())
+		} else if addr, err := netip.ParseAddr(t); err == nil {
+			prefixes = append(prefixes, netip.PrefixFrom(addr.Unmap(), addr.Unmap().BitLen()))
+		}
+	}
+	isTrusted := func(s string) bool {
+		addr, err := netip.ParseAddr(strings.TrimSpace(s))
+		if err != nil {
+			return false
+		}
+		addr = addr.Unmap()
+		for _, p := range prefixes {
+			if p.Contains(addr) {
+				return true
+			}
+		}
+		return false
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			peer, _, err := net.SplitHostPort(r.RemoteAddr)
+			if err != nil {
+				peer = r.RemoteAddr
+			}
+			if len(prefixes) > 0 && isTrusted(peer) {
+				if ip := forwardedIP(r.Header, isTrusted); ip != "" {
+					r.RemoteAddr = ip
+				}
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// returns the client address a trusted proxy forwarded, "" when there is none
+func forwardedIP(h http.Header, isTrusted func(string) bool) string {
+	var hops []string
+	for _, v := range h.Values("X-Forwarded-For") {
+		hops = append(hops, strings.Split(v, ",")...)
+	}
+	// the rightmost entries were added by our own proxies
+	for i := len(hops) - 1; i >= 0; i-- {
+		hop := strings.TrimSpace(hops[i])
+		if _, err := netip.ParseAddr(hop); err != nil {
+			return ""
+		}
+		if !isTrusted(hop) || i == 0 {
+			return hop
+		}
+	}
+	// proxies that don't append to X-Forwarded-For
+	if ip := strings.TrimSpace(h.Get("X-Real-IP")); ip != "" {
+		if _, err := netip.ParseAddr(ip); err == nil {
+			return ip
+		}
+	}
+	return ""
+}
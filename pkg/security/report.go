@@ -0,0 +1,85 @@
+package security
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+
+	"go-rest-template/pkg/logger"
+)
+
+const (
+	// reports are small, anything larger is not a browser report
+	maxReportBytes = 64 << 10
+	// browsers batch a handful of reports, the rest of a larger batch is dropped
+	maxReportsPerRequest = 20
+	// report values are URLs and directives, longer ones are cut
+	maxReportValueLen = 512
+)
+
+// report fields that are logged, keyed by their legacy csp-report name with the
+// Reporting API name each one maps from. Anything else a client sends is ignored.
+var reportFields = map[string]string{
+	"blocked-uri":        "blockedURL",
+	"violated-directive": "effectiveDirective",
+	"document-uri":       "documentURL",
+}
+
+// ReportHandler logs Content-Security-Policy violation reports, both the legacy
+// application/csp-report format and the Reporting API's application/reports+json.
+// Only the known fields of at most maxReportsPerRequest reports are logged; mount
+// it behind RateLimit as anyone can post to it.
+func ReportHandler(w http.ResponseWriter, r *http.Request) {
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxReportBytes))
+	if err != nil {
+		w.WriteHeader(http.StatusRequestEntityTooLarge)
+		return
+	}
+
+	var reports []map[string]any
+	var legacy struct {
+		Report map[string]any `json:"csp-report"`
+	}
+	switch {
+	case json.Unmarshal(body, &legacy) == nil && legacy.Report != nil:
+		reports = append(reports, legacy.Report)
+	case json.Unmarshal(body, &reports) == nil:
+		for i, report := range reports {
+			if inner, ok := report["body"].(map[string]any); ok {
+				reports[i] = inner
+			}
+		}
+	default:
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	log := logger.FromContext(r.Context())
+	if len(reports) > maxReportsPerRequest {
+		log.Warn("content security policy reports dropped", logger.Fields{"dropped": len(reports) - maxReportsPerRequest})
+		reports = reports[:maxReportsPerRequest]
+	}
+	for _, report := range reports {
+		log.Warn("content security policy violation", logger.Fields{"csp_report": reportSummary(report), "user_agent": r.UserAgent()})
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// keeps the known string fields of a report, under their legacy names
+func reportSummary(report map[string]any) map[string]string {
+	summary := map[string]string{}
+	for name, reportingName := range reportFields {
+		value, ok := report[name].(string)
+		if !ok {
+			value, ok = report[reportingName].(string)
+		}
+		if !ok {
+			continue
+		}
+		if len(value) > maxReportValueLen {
+			value = value[:maxReportValueLen]
+		}
+		summary[name] = value
+	}
+	return summary
+}
@@ -0,0 +1,160 @@
+package tlsconfig
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync/atomic"
+	"time"
+
+	"go-rest-template/pkg/config"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+// client certificate modes for TLS_CLIENT_AUTH
+var clientAuthModes = map[string]tls.ClientAuthType{
+	"none":     tls.NoClientCert,
+	"optional": tls.VerifyClientCertIfGiven,
+	"require":  tls.RequireAndVerifyClientCert,
+}
+
+var minVersions = map[string]uint16{
+	"1.2": tls.VersionTLS12,
+	"1.3": tls.VersionTLS13,
+}
+
+// Reloader serves the certificate and client CAs from the configured files and
+// picks up new versions of them without a restart
+type Reloader struct {
+	certFile, keyFile, caFile string
+	clientAuth                tls.ClientAuthType
+	minVersion                uint16
+	current                   atomic.Pointer[tls.Config]
+}
+
+// Enabled reports whether TLS_CERT_FILE and TLS_KEY_FILE are set
+func Enabled() bool {
+	cfg := config.APP()
+	return cfg.TLS_CERT_FILE != "" && cfg.TLS_KEY_FILE != ""
+}
+
+// New loads the certificate, key and client CAs named in the configuration and
+// returns a server TLS config that always uses the latest loaded files, with
+// HTTP/2 enabled
+func New() (*tls.Config, *Reloader, error) {
+	cfg := config.APP()
+	r := &Reloader{
+		certFile:   cfg.TLS_CERT_FILE,
+		keyFile:    cfg.TLS_KEY_FILE,
+		caFile:     cfg.TLS_CLIENT_CA_FILE,
+		clientAuth: clientAuthModes[cfg.TLS_CLIENT_AUTH],
+		minVersion: minVersions[cfg.TLS_MIN_VERSION],
+	}
+	if err := r.Reload(); err != nil {
+		return nil, nil, err
+	}
+	return &tls.Config{
+		MinVersion: r.minVersion,
+		NextProtos: []string{"h2", "http/1.1"},
+		GetConfigForClient: func(*tls.ClientHelloInfo) (*tls.Config, error) {
+			return r.current.Load(), nil
+		},
+	}, r, nil
+}
+
+// Reload reads the files again and swaps them in, keeping the previous ones on error
+func (r *Reloader) Reload() error {
+	cert, err := tls.LoadX509KeyPair(r.certFile, r.keyFile)
+	if err != nil {
+		return fmt.Errorf("loading TLS certificate: %w", err)
+	}
+	next := &tls.Config{
+		MinVersion:   r.minVersion,
+		NextProtos:   []string{"h2", "http/1.1"},
+		Certificates: []tls.Certificate{cert},
+		ClientAuth:   r.clientAuth,
+	}
+	if r.caFile != "" {
+		pem, err := os.ReadFile(r.caFile)
+		if err != nil {
+			return fmt.Errorf("loading client CA: %w", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return fmt.Errorf("loading client CA: no certificates found in %s", r.caFile)
+		}
+		next.ClientCAs = pool
+	}
+	r.current.Store(next)
+	return nil
+}
+
+// Watch reloads the files when any of them changes until ctx is done, passing
+// the outcome of each reload to report
+func (r *Reloader) Watch(ctx context.Context, report func(error)) error {
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return err
+	}
+	defer watcher.Close()
+
+	files := map[string]bool{}
+	for _, f := range []string{r.certFile, r.keyFile, r.caFile} {
+		if f == "" {
+			continue
+		}
+		abs, err := filepath.Abs(f)
+		if err != nil {
+			return err
+		}
+		files[abs] = true
+		// watch the directory, certificate managers replace files and swap symlinks
+		if err := watcher.Add(filepath.Dir(abs)); err != nil {
+			return err
+		}
+	}
+
+	// cert and key are usually written one after the other, reload once both settle
+	debounce := time.NewTimer(time.Hour)
+	debounce.Stop()
+	defer debounce.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case event := <-watcher.Events:
+			// Kubernetes secrets update a ..data symlink next to the files
+			if files[filepath.Clean(event.Name)] || filepath.Base(event.Name) == "..data" {
+				debounce.Reset(500 * time.Millisecond)
+			}
+		case <-debounce.C:
+			report(r.Reload())
+		case err := <-watcher.Errors:
+			report(fmt.Errorf("watching TLS files: %w", err))
+		}
+	}
+}
+
+// RedirectHandler redirects every request to the same host and path over HTTPS
+// on httpsPort
+func RedirectHandler(httpsPort uint) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		host := r.Host
+		if h, _, err := net.SplitHostPort(host); err == nil {
+			host = h
+		}
+		if httpsPort != 443 {
+			host = net.JoinHostPort(host, strconv.FormatUint(uint64(httpsPort), 10))
+		}
+		target := "https://" + host + r.URL.RequestURI()
+		http.Redirect(w, r, target, http.StatusPermanentRedirect)
+	})
+}
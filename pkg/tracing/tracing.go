@@ -0,0 +1,115 @@
+package tracing
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"go-rest-template/pkg/config"
+	"net/url"
+	"os"
+	"path/filepath"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.37.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const (
+	instrumentation = "go-rest-template"
+	serviceName     = "go-rest-template"
+)
+
+// returns the tracer used for the application's spans
+func Tracer() trace.Tracer {
+	return otel.Tracer(instrumentation)
+}
+
+// Setup installs the W3C trace-context propagator and, unless TRACE_EXPORTER is
+// "none", a tracer provider exporting sampled spans:
+//
+//	otlp             OTLP over HTTP, configured with the standard OTEL_EXPORTER_OTLP_* variables
+//	stdout           spans as JSON on stdout
+//	file://path      spans as JSON lines appended to a file
+//
+// The returned function flushes pending spans and must be called on shutdown.
+func Setup(ctx context.Context) (func(context.Context) error, error) {
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}))
+
+	cfg := config.APP()
+	if cfg.TRACE_EXPORTER == "none" {
+		return func(context.Context) error { return nil }, nil
+	}
+
+	exporter, closeOutput, err := newExporter(ctx, cfg.TRACE_EXPORTER)
+	if err != nil {
+		return nil, fmt.Errorf("trace exporter %q: %w", cfg.TRACE_EXPORTER, err)
+	}
+
+	// OTEL_SERVICE_NAME and OTEL_RESOURCE_ATTRIBUTES override the defaults
+	res, err := resource.New(ctx,
+		resource.WithTelemetrySDK(),
+		resource.WithAttributes(
+			semconv.ServiceName(serviceName),
+			semconv.DeploymentEnvironmentName(cfg.GO_ENV),
+		),
+		resource.WithFromEnv(),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(res),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(cfg.TRACE_SAMPLE_RATIO))),
+	)
+	otel.SetTracerProvider(provider)
+
+	return func(ctx context.Context) error {
+		return errors.Join(provider.Shutdown(ctx), closeOutput())
+	}, nil
+}
+
+func newExporter(ctx context.Context, target string) (sdktrace.SpanExporter, func() error, error) {
+	noClose := func() error { return nil }
+	switch target {
+	case "otlp":
+		exporter, err := otlptracehttp.New(ctx)
+		return exporter, noClose, err
+	case "stdout":
+		exporter, err := stdouttrace.New(stdouttrace.WithWriter(os.Stdout))
+		return exporter, noClose, err
+	}
+
+	u, err := url.Parse(target)
+	if err != nil || u.Scheme != "file" {
+		return nil, nil, errors.New("use none, otlp, stdout or a file:// URI")
+	}
+	path := filepath.FromSlash(u.Host + u.Path)
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return nil, nil, err
+	}
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return nil, nil, err
+	}
+	exporter, err := stdouttrace.New(stdouttrace.WithWriter(file))
+	if err != nil {
+		file.Close()
+		return nil, nil, err
+	}
+	return exporter, file.Close, nil
+}
+
+// starts a span for work inside the application, e.g.
+//
+//	ctx, span := tracing.Start(ctx, "bcrypt.hash")
+//	defer span.End()
+func Start(ctx context.Context, name string, opts ...trace.SpanStartOption) (context.Context, trace.Span) {
+	return Tracer().Start(ctx, name, opts...)
+}
@@ -1,42 +1,195 @@
 package pkg
 
 import (
-	"encoding/json"
+	"context"
 	"errors"
-	"io"
+	"fmt"
 	"net/http"
 	"reflect"
 	"regexp"
 	"strings"
 
+	ut "github.com/go-playground/universal-translator"
 	"github.com/go-playground/validator/v10"
 )
 
-// BindAndValidate decodes JSON body from the http.Request and validates it.
+// BindAndValidate binds the request body (JSON, form-urlencoded or multipart), the
+// query string and the path parameters to T and validates it, using DefaultBindOptions.
 // Returns: (payload), (map of field errors keyed by json path), (error)
 func BindAndValidate[T any](r *http.Request) (T, map[string]string, error) {
+	return BindAndValidateWith[T](r, DefaultBindOptions)
+}
+
+// BindAndValidateWith is BindAndValidate with explicit options. Binding errors are
+// *api.HTTPError: 400 for malformed bodies, 413 for oversized ones and 415 for an
+// unsupported Content-Type. Payloads implementing ContextValidator are checked after
+// the tags and their field errors are returned in the same format.
+func BindAndValidateWith[T any](r *http.Request, opts BindOptions) (T, map[string]string, error) {
+	var payload T
+	fieldErrors, err := bindAndValidate(r, &payload, opts)
+	if err != nil || fieldErrors != nil {
+		return payload, fieldErrors, err
+	}
+	if cv, ok := any(&payload).(ContextValidator); ok {
+		fieldErrors, err = cv.Validate(validationContext(r))
+		return payload, nonEmpty(fieldErrors), err
+	}
+	return payload, nil, nil
+}
+
+// BindAndValidateUsing is BindAndValidateWith for payloads implementing
+// ValidatorWith[D], passing deps to their Validate once the tags pass:
+//
+//	body, fieldErrors, err := pkg.BindAndValidateUsing[dto.User_Signup_Request, dto.UserLookup](r, pkg.DefaultBindOptions, h.repo)
+func BindAndValidateUsing[T, D any](r *http.Request, opts BindOptions, deps D) (T, map[string]string, error) {
 	var payload T
+	fieldErrors, err := bindAndValidate(r, &payload, opts)
+	if err != nil || fieldErrors != nil {
+		return payload, fieldErrors, err
+	}
+	v, ok := any(&payload).(ValidatorWith[D])
+	if !ok {
+		return payload, nil, fmt.Errorf("%T does not implement pkg.ValidatorWith[%v]", &payload, reflect.TypeFor[D]())
+	}
+	fieldErrors, err = v.Validate(validationContext(r), deps)
+	return payload, nonEmpty(fieldErrors), err
+}
 
-	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
-		if errors.Is(err, io.EOF) {
-			return payload, nil, errors.New("request body is empty")
+// binds the request to payload and checks the validate tags
+func bindAndValidate(r *http.Request, payload any, opts BindOptions) (map[string]string, error) {
+	fieldErrors, err := bindBody(r, payload, opts)
+	if errors.Is(err, errEmptyBody) && hasParamFields(reflect.TypeOf(payload)) {
+		err = nil // requests bound only from the query string or path have no body
+	}
+	if err != nil {
+		return nil, err
+	}
+	for k, v := range bindParams(r, payload) {
+		if fieldErrors == nil {
+			fieldErrors = map[string]string{}
 		}
-		return payload, nil, err
+		fieldErrors[k] = v
+	}
+	if fieldErrors != nil {
+		return fieldErrors, nil
 	}
 
-	validate := validator.New()
 	if err := validate.Struct(payload); err != nil {
-		if verrs, ok := err.(validator.ValidationErrors); ok {
-			return payload, TagValidationErrors(verrs, payload), nil
+		verrs, ok := err.(validator.ValidationErrors)
+		if !ok {
+			return nil, err
 		}
-		return payload, nil, err
+		return TranslateValidationErrors(verrs, payload, TranslatorFor(r)), nil
 	}
+	return nil, nil
+}
 
-	return payload, nil, nil
+// the context passed to the payload rules, carrying the request's translator for
+// ValidationMessage
+func validationContext(r *http.Request) context.Context {
+	return WithTranslator(r.Context(), TranslatorFor(r))
+}
+
+func nonEmpty(fieldErrors map[string]string) map[string]string {
+	if len(fieldErrors) == 0 {
+		return nil
+	}
+	return fieldErrors
+}
+
+// ContextValidator is implemented by payloads with rules that need the request
+// context. It runs only when the tag rules pass. Validate returns field errors keyed
+// by JSON path, the error is for failures of the check itself.
+type ContextValidator interface {
+	Validate(ctx context.Context) (map[string]string, error)
+}
+
+// ValidatorWith is implemented by payloads with rules that need dependencies D, such
+// as a repository for database lookups, which BindAndValidateUsing passes in so the
+// payload never holds them. It runs only when the tag rules pass; the error is for
+// failures of the check itself (e.g. the database is down).
+type ValidatorWith[D any] interface {
+	Validate(ctx context.Context, deps D) (map[string]string, error)
+}
+
+type validationContextKey string
+
+const translatorContextKey validationContextKey = "translator"
+
+// WithTranslator returns a context whose ValidationMessage calls use trans, for
+// validation that happens outside BindAndValidate.
+func WithTranslator(ctx context.Context, trans ut.Translator) context.Context {
+	return context.WithValue(ctx, translatorContextKey, trans)
+}
+
+// ValidationMessage returns the message template for key in the locale of the request
+// being validated, for use inside ContextValidator and ValidatorWith. Missing params
+// are empty.
+func ValidationMessage(ctx context.Context, key string, params ...string) string {
+	trans, ok := ctx.Value(translatorContextKey).(ut.Translator)
+	if !ok {
+		trans = Translator(defaultLocale)
+	}
+	if msg, err := translate(trans, key, params...); err == nil {
+		return msg
+	}
+	if msg, err := translate(Translator(defaultLocale), key, params...); err == nil {
+		return msg
+	}
+	return key
+}
+
+// shared validator, it caches struct metadata across requests
+var validate = newValidator()
+
+var (
+	slugRegex        = regexp.MustCompile(`^[a-z0-9]+(?:-[a-z0-9]+)*$`)
+	passwordSymbolRe = regexp.MustCompile(`[^A-Za-z0-9]`)
+)
+
+func newValidator() *validator.Validate {
+	v := validator.New()
+	v.RegisterValidation("slug", func(fl validator.FieldLevel) bool {
+		return slugRegex.MatchString(fl.Field().String())
+	})
+	v.RegisterValidation("strong_password", func(fl validator.FieldLevel) bool {
+		s := fl.Field().String()
+		return len(s) >= 8 &&
+			strings.ContainsAny(s, "ABCDEFGHIJKLMNOPQRSTUVWXYZ") &&
+			strings.ContainsAny(s, "abcdefghijklmnopqrstuvwxyz") &&
+			strings.ContainsAny(s, "0123456789") &&
+			passwordSymbolRe.MatchString(s)
+	})
+	return v
+}
+
+// RegisterValidation adds a custom validation tag with its English message template,
+// use AddValidationMessage for other locales. It must be called before validating.
+func RegisterValidation(tag string, fn validator.Func, message string) error {
+	if err := validate.RegisterValidation(tag, fn); err != nil {
+		return err
+	}
+	return AddValidationMessage(defaultLocale, tag, message)
+}
+
+// RegisterStructValidation adds a cross-field rule for a struct type. Errors are
+// reported with validator.StructLevel.ReportError and a tag that has a message.
+func RegisterStructValidation(fn validator.StructLevelFunc, types ...any) {
+	validate.RegisterStructValidation(fn, types...)
+}
+
+// Validate validates a struct with the shared validator.
+func Validate(s any) error {
+	return validate.Struct(s)
 }
 
 // TagValidationErrors converts validator.ValidationErrors to a map keyed by JSON field paths.
 func TagValidationErrors(errs validator.ValidationErrors, obj any) map[string]string {
+	return TranslateValidationErrors(errs, obj, Translator(defaultLocale))
+}
+
+// TranslateValidationErrors is TagValidationErrors with messages in the translator's locale.
+func TranslateValidationErrors(errs validator.ValidationErrors, obj any, trans ut.Translator) map[string]string {
 	errors := make(map[string]string)
 
 	reflected := reflect.TypeOf(obj)
@@ -48,12 +201,28 @@ func TagValidationErrors(errs validator.ValidationErrors, obj any) map[string]st
 		nsParts := strings.Split(e.StructNamespace(), ".")
 		// skip root struct name in namespace
 		jsonPath := buildJSONTagPath(reflected, nsParts[1:])
-		errors[jsonPath] = validationMessageForTag(e.Tag(), e.Param())
+		param := e.Param()
+		if isFieldComparison(e.Tag()) {
+			// refer to the other field by its JSON name
+			parent := append(nsParts[1:len(nsParts)-1:len(nsParts)-1], param)
+			other := buildJSONTagPath(reflected, parent)
+			param = other[strings.LastIndex(other, ".")+1:]
+		}
+		errors[jsonPath] = translateFieldError(trans, e, param)
 	}
 
 	return errors
 }
 
+// reports whether the tag compares against another field of the struct
+func isFieldComparison(tag string) bool {
+	switch tag {
+	case "eqfield", "nefield", "gtfield", "gtefield", "ltfield", "ltefield":
+		return true
+	}
+	return false
+}
+
 var arrayIndexRegex = regexp.MustCompile(`^(\w+)(\[\d+\])?$`)
 
 // buildJSONTagPath builds the error key by mapping struct fields to their JSON tags,
@@ -107,45 +276,3 @@ func buildJSONTagPath(t reflect.Type, parts []string) string {
 
 	return strings.Join(path, ".")
 }
-
-// validationMessageForTag returns human-friendly validation error messages.
-func validationMessageForTag(tag, param string) string {
-	switch tag {
-	case "required":
-		return "Missing required field"
-	case "email":
-		return "Invalid email format"
-	case "min":
-		return "Must be at least " + param + " characters"
-	case "max":
-		return "Must be at most " + param + " characters"
-	case "len":
-		return "Must be exactly " + param + " characters long"
-	case "gte":
-		return "Must be greater than or equal to " + param
-	case "lte":
-		return "Must be less than or equal to " + param
-	case "gt":
-		return "Must be greater than " + param
-	case "lt":
-		return "Must be less than " + param
-	case "number":
-		return "Must be a valid number"
-	case "numeric":
-		return "Must contain only numeric characters"
-	case "oneof":
-		return "Must be one of: " + param
-	case "url":
-		return "Must be a valid URL"
-	case "uuid":
-		return "Must be a valid UUID"
-	case "alphanum":
-		return "Must contain only alphanumeric characters"
-	case "alpha":
-		return "Must contain only alphabetic characters"
-	case "boolean":
-		return "Must be a valid boolean value"
-	default:
-		return "Invalid value"
-	}
-}